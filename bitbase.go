@@ -0,0 +1,707 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`bytes`
+	`compress/flate`
+	`encoding/binary`
+	`fmt`
+	`io/ioutil`
+	`path/filepath`
+	`sort`
+)
+
+// Win/draw/loss bitbases for endgames with up to four pieces (kings
+// included) are generated by retrograde analysis. The value of each position
+// is stored from the point of view of the side to move and takes two bits:
+// draw (or illegal position), win, or loss. En-passant captures, castling,
+// and the 50 moves rule are ignored.
+//
+// Positions are indexed by the side to move, white king square, and the
+// squares of the remaining pieces in signature order. White king is always
+// placed in A1-D1-D4 triangle for pawnless endgames or on A-D files when
+// there are pawns.
+//
+// Bitbase file starts with the magic number followed by the length and the
+// name of material signature, and the number of positions. The rest is
+// deflate-compressed array of values, four positions per byte.
+
+const (
+	bbDraw = iota		// Draw or illegal position.
+	bbWin			// Side to move wins.
+	bbLoss			// Side to move loses.
+	bbUnknown		// Not resolved yet (generation only).
+	bbInvalid		// Illegal position (generation only).
+)
+
+const (
+	bbPieces = 4		// Maximum number of pieces, kings included.
+	bbMagic = `DBB1`	// Bitbase file magic number.
+	bbExtension = `.dbb`	// Bitbase file name extension.
+)
+
+type Bitbase struct {
+	name     string		// Material signature with stronger side first, ex. `KRKP`.
+	pieces   []Piece	// White pieces followed by black pieces, kings first.
+	material uint64		// Material key of the signature.
+	pawns    bool		// True if there are pawns.
+	kings    []int		// Canonical white king squares.
+	size     int		// Number of positions.
+	data     []uint8	// Four positions per byte.
+	values   []uint8	// One position per byte while generating.
+}
+
+// Pieces and their squares as seen by the generator.
+type BitbaseSetup struct {
+	count   int
+	pieces  [bbPieces]Piece
+	squares [bbPieces]int
+}
+
+// Loaded or generated bitbases indexed by material key of either color.
+var bitbases = map[uint64]*Bitbase{}
+
+// Returns bitbase for the given material signature, ex. `KRKP` or `KPKR`.
+// The bitbase gets generated or loaded separately.
+func NewBitbase(signature string) (*Bitbase, error) {
+	pieces, err := bbParse(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	bb := &Bitbase{ pieces: pieces, material: bbMaterial(pieces) }
+	for _, piece := range pieces {
+		bb.name += string(bbChar(piece))
+		bb.pawns = bb.pawns || piece.isPawn()
+	}
+
+	for square := A1; square <= H8; square++ {
+		if row, col := coordinate(square); col <= 3 && (bb.pawns || row <= col) {
+			bb.kings = append(bb.kings, square)
+		}
+	}
+	if !bb.pawns {
+		sort.Slice(bb.kings, func(i, j int) bool {
+			return tbMapA1D1D4[bb.kings[i]] < tbMapA1D1D4[bb.kings[j]]
+		})
+	}
+	bb.size = 2 * len(bb.kings) << uint(6 * (len(pieces) - 1))
+
+	return bb, nil
+}
+
+// Parses material signature and returns the list of pieces so that the
+// stronger side becomes white.
+func bbParse(signature string) ([]Piece, error) {
+	sides := [2][]Piece{}
+
+	color := -1
+	for _, char := range signature {
+		kind := map[rune]int{ 'K': King, 'Q': Queen, 'R': Rook, 'B': Bishop, 'N': Knight, 'P': Pawn }[char]
+		if kind == 0 || (color < 0 && kind != King) {
+			return nil, fmt.Errorf("invalid material signature %q", signature)
+		}
+		if kind == King {
+			if color++; color > Black {
+				return nil, fmt.Errorf("invalid material signature %q", signature)
+			}
+		}
+		sides[color] = append(sides[color], Piece(kind | color))
+	}
+
+	if color != Black || len(sides[White]) + len(sides[Black]) > bbPieces {
+		return nil, fmt.Errorf("material signature %q must have two kings and up to %d pieces", signature, bbPieces)
+	}
+
+	// Sort each side by piece kind (king first), then make the stronger side
+	// white.
+	for color := White; color <= Black; color++ {
+		sort.Slice(sides[color], func(i, j int) bool {
+			return sides[color][i].kind() > sides[color][j].kind()
+		})
+	}
+	if bbStronger(sides[Black], sides[White]) {
+		sides[White], sides[Black] = sides[Black], sides[White]
+		for color := White; color <= Black; color++ {
+			for i := range sides[color] {
+				sides[color][i] ^= 1
+			}
+		}
+	}
+
+	return append(sides[White], sides[Black]...), nil
+}
+
+// Returns true if the first side has more valuable material. Equal material
+// is compared piece by piece.
+func bbStronger(one, two []Piece) bool {
+	weight := 0
+	for _, piece := range one {
+		weight += piece.value()
+	}
+	for _, piece := range two {
+		weight -= piece.value()
+	}
+	if weight != 0 {
+		return weight > 0
+	}
+
+	for i := 0; i < len(one) && i < len(two); i++ {
+		if one[i].kind() != two[i].kind() {
+			return one[i].kind() > two[i].kind()
+		}
+	}
+	return len(one) > len(two)
+}
+
+// Returns colorless ASCII code for the piece, pawns included.
+func bbChar(piece Piece) byte {
+	if piece.isPawn() {
+		return 'P'
+	}
+	return piece.char()
+}
+
+// Returns material key for the list of pieces: four bits per piece count.
+func bbMaterial(pieces []Piece) (key uint64) {
+	for _, piece := range pieces {
+		key += 1 << (4 * uint(piece))
+	}
+	return key
+}
+
+// Returns material key of the position.
+func (p *Position) bbMaterial() (key uint64) {
+	for piece := Pawn; piece <= BlackKing; piece++ {
+		key += uint64(p.outposts[piece].count()) << (4 * uint(piece))
+	}
+	return key
+}
+
+// Returns true if neither side is able to win with given material: there
+// are no pawns, rooks, or queens, and each side has one minor piece at most.
+func bbInsufficient(material uint64) bool {
+	minors := [2]uint64{}
+	for color := White; color <= Black; color++ {
+		if material >> (4 * uint(pawn(uint8(color)))) & 0xF != 0 ||
+		   material >> (4 * uint(rook(uint8(color)))) & 0xF != 0 ||
+		   material >> (4 * uint(queen(uint8(color)))) & 0xF != 0 {
+			return false
+		}
+		minors[color] = material >> (4 * uint(knight(uint8(color)))) & 0xF + material >> (4 * uint(bishop(uint8(color)))) & 0xF
+	}
+
+	return minors[White] <= 1 && minors[Black] <= 1
+}
+
+// Registers the bitbase for probing.
+func (bb *Bitbase) register() *Bitbase {
+	bitbases[bb.material] = bb
+	bitbases[bbMaterial(bb.mirrored())] = bb
+
+	return bb
+}
+
+// Returns the list of pieces with colors reversed.
+func (bb *Bitbase) mirrored() (pieces []Piece) {
+	for _, piece := range bb.pieces {
+		pieces = append(pieces, piece ^ 1)
+	}
+	return pieces
+}
+
+// Returns stored value of the position.
+func (bb *Bitbase) value(index int) int {
+	if bb.values != nil {
+		return int(bb.values[index])
+	}
+	return int(bb.data[index >> 2] >> (uint(index & 3) << 1)) & 3
+}
+
+// Transforms the squares so that white king ends up in canonical region.
+// Pawnless endgames make use of all eight symmetries of the board; when
+// the king is on A1-H8 diagonal the first piece off the diagonal decides
+// whether to transpose.
+func (bb *Bitbase) normalize(squares []int) {
+	transform := func(fn func(int) int) {
+		for i := range squares {
+			squares[i] = fn(squares[i])
+		}
+	}
+
+	if col(squares[0]) > 3 {
+		transform(func(square int) int { return square ^ 7 })
+	}
+	if bb.pawns {
+		return
+	}
+	if row(squares[0]) > 3 {
+		transform(func(square int) int { return square ^ 56 })
+	}
+
+	transpose := offDiagonal(squares[0])
+	for i := 1; transpose == 0 && i < len(squares); i++ {
+		transpose = offDiagonal(squares[i])
+	}
+	if transpose > 0 {
+		transform(func(square int) int { return (square >> 3) | ((square & 7) << 3) })
+	}
+}
+
+// Returns bitbase index of normalized squares.
+func (bb *Bitbase) index(color uint8, squares []int) int {
+	king := squares[0]
+	if bb.pawns {
+		king = row(king) * 4 + col(king)
+	} else {
+		king = tbMapA1D1D4[king]
+	}
+
+	index := int(color) * len(bb.kings) + king
+	for _, square := range squares[1:] {
+		index = index << 6 + square
+	}
+
+	return index
+}
+
+// Decodes bitbase index into side to move and squares.
+func (bb *Bitbase) decode(index int, squares []int) (color uint8) {
+	for i := len(squares) - 1; i > 0; i-- {
+		squares[i] = index & 63
+		index >>= 6
+	}
+	squares[0] = bb.kings[index % len(bb.kings)]
+
+	return uint8(index / len(bb.kings))
+}
+
+// Returns generator setup for the index.
+func (bb *Bitbase) setup(index int) (setup BitbaseSetup, color uint8) {
+	setup.count = len(bb.pieces)
+	copy(setup.pieces[:], bb.pieces)
+	color = bb.decode(index, setup.squares[:setup.count])
+
+	return
+}
+
+// Looks up the setup that matches bitbase material in either color.
+func (bb *Bitbase) probe(setup BitbaseSetup, color uint8) int {
+	if bbMaterial(setup.pieces[:setup.count]) != bb.material {
+		for i := 0; i < setup.count; i++ {
+			setup.pieces[i] ^= 1
+			setup.squares[i] ^= 56
+		}
+		color ^= 1
+	}
+
+	// Arrange the squares in signature order.
+	squares, taken := make([]int, len(bb.pieces)), 0
+	for i, piece := range bb.pieces {
+		for j := 0; j < setup.count; j++ {
+			if setup.pieces[j] == piece && taken & (1 << uint(j)) == 0 {
+				squares[i], taken = setup.squares[j], taken | (1 << uint(j))
+				break
+			}
+		}
+	}
+	bb.normalize(squares)
+
+	return bb.value(bb.index(color, squares))
+}
+
+// Returns bitbase value of the position from the point of view of the side
+// to move.
+func (p *Position) probeBitbase() (int, bool) {
+	if len(bitbases) == 0 || p.castles != 0 || p.enpassant != 0 || p.board.count() > bbPieces {
+		return bbDraw, false
+	}
+
+	bb := bitbases[p.bbMaterial()]
+	if bb == nil || bb.data == nil {
+		return bbDraw, false
+	}
+
+	setup := BitbaseSetup{}
+	for board := p.board; board.any(); setup.count++ {
+		square := board.pop()
+		setup.pieces[setup.count], setup.squares[setup.count] = p.pieces[square], square
+	}
+
+	return bb.probe(setup, p.color), true
+}
+
+// Returns the value of position that has different material, generating
+// missing bitbases as necessary.
+func bbProbe(setup BitbaseSetup, color uint8) (int, error) {
+	material := bbMaterial(setup.pieces[:setup.count])
+	if bbInsufficient(material) {
+		return bbDraw, nil
+	}
+
+	bb := bitbases[material]
+	if bb == nil {
+		sides := [2]string{ `K`, `K` }
+		for _, piece := range setup.pieces[:setup.count] {
+			if !piece.isKing() {
+				sides[piece.color()] += string(bbChar(piece))
+			}
+		}
+		var err error
+		if bb, err = NewBitbase(sides[White] + sides[Black]); err != nil {
+			return bbDraw, err
+		}
+		if err = bb.Generate(); err != nil {
+			return bbDraw, err
+		}
+	}
+
+	return bb.probe(setup, color), nil
+}
+
+// Returns attacks of the piece for given board occupancy.
+func bbAttacks(piece Piece, square int, board Bitmask) Bitmask {
+	switch piece.kind() {
+	case Pawn:
+		return pawnAttacks[piece.color()][square]
+	case Knight:
+		return knightMoves[square]
+	case Bishop:
+		return bishopMagicMoves[square][((bishopMagic[square].mask & board) * bishopMagic[square].magic) >> 55]
+	case Rook:
+		return rookMagicMoves[square][((rookMagic[square].mask & board) * rookMagic[square].magic) >> 52]
+	case Queen:
+		return bbAttacks(Bishop, square, board) | bbAttacks(Rook, square, board)
+	}
+	return kingMoves[square]
+}
+
+// Returns occupied squares of the setup.
+func (s *BitbaseSetup) board() (board Bitmask) {
+	for i := 0; i < s.count; i++ {
+		board.set(s.squares[i])
+	}
+	return board
+}
+
+// Returns true if the square is attacked by pieces of the given color.
+func (s *BitbaseSetup) attacked(square int, color uint8, board Bitmask) bool {
+	for i := 0; i < s.count; i++ {
+		if s.pieces[i].color() == color && bbAttacks(s.pieces[i], s.squares[i], board).on(square) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the square of the king of the given color.
+func (s *BitbaseSetup) king(color uint8) int {
+	for i := 0; i < s.count; i++ {
+		if s.pieces[i] == king(color) {
+			return s.squares[i]
+		}
+	}
+	return -1
+}
+
+// Checks whether the index represents legal normalized position.
+func (bb *Bitbase) legal(index int) bool {
+	setup, color := bb.setup(index)
+	squares := setup.squares[:setup.count]
+
+	board := setup.board()
+	if board.count() != setup.count || distance[squares[0]][setup.king(Black)] <= 1 {
+		return false
+	}
+	for i, piece := range setup.pieces[:setup.count] {
+		if piece.isPawn() && (row(squares[i]) == A1H1 || row(squares[i]) == A8H8) {
+			return false
+		}
+	}
+	if setup.attacked(setup.king(color ^ 1), color, board) {
+		return false
+	}
+
+	normalized := append([]int{}, squares...)
+	bb.normalize(normalized)
+
+	return bb.index(color, normalized) == index
+}
+
+// Evaluates the position by looking at all its successors. Returns either
+// the final value or bbUnknown if some successors are not resolved yet.
+func (bb *Bitbase) evaluate(index int) (int, error) {
+	setup, color := bb.setup(index)
+	board := setup.board()
+
+	moves, unresolved := 0, false
+	for i := 0; i < setup.count; i++ {
+		piece, from := setup.pieces[i], setup.squares[i]
+		if piece.color() != color {
+			continue
+		}
+
+		var targets Bitmask
+		if piece.isPawn() {
+			step := let(color == White, 8, -8)
+			if push := from + step; board.off(push) {
+				targets.set(push)
+				if row(from) == let(color == White, A2H2, A7H7) && board.off(push + step) {
+					targets.set(push + step)
+				}
+			}
+			for attacks := pawnAttacks[color][from]; attacks.any(); {
+				if square := attacks.pop(); board.on(square) {
+					targets.set(square)
+				}
+			}
+		} else {
+			targets = bbAttacks(piece, from, board)
+		}
+
+		for targets.any() {
+			to := targets.pop()
+
+			// Make the move and remove captured piece, if any.
+			next, captured := setup, false
+			next.squares[i] = to
+			for j := 0; j < next.count; j++ {
+				if j != i && next.squares[j] == to {
+					if next.pieces[j].color() == color {
+						break
+					}
+					copy(next.pieces[j:], next.pieces[j+1:next.count])
+					copy(next.squares[j:], next.squares[j+1:next.count])
+					next.count, captured = next.count - 1, true
+					break
+				}
+			}
+			if !captured && board.on(to) {
+				continue // Own piece.
+			}
+
+			promotions := []Piece{ piece }
+			if piece.isPawn() && (row(to) == A1H1 || row(to) == A8H8) {
+				promotions = []Piece{ queen(color), rook(color), bishop(color), knight(color) }
+			}
+
+			for _, promo := range promotions {
+				for j := 0; j < next.count; j++ {
+					if next.squares[j] == to {
+						next.pieces[j] = promo
+					}
+				}
+				if next.attacked(next.king(color), color ^ 1, next.board()) {
+					break // Illegal move regardless of promotion piece.
+				}
+				moves++
+
+				var result int
+				if captured || promo != piece {
+					var err error
+					if result, err = bbProbe(next, color ^ 1); err != nil {
+						return bbUnknown, err
+					}
+				} else {
+					squares := next.squares
+					bb.normalize(squares[:next.count])
+					result = bb.value(bb.index(color ^ 1, squares[:next.count]))
+				}
+
+				if result == bbLoss {
+					return bbWin, nil
+				} else if result != bbWin {
+					unresolved = true
+				}
+			}
+		}
+	}
+
+	if moves == 0 {
+		if setup.attacked(setup.king(color), color ^ 1, board) {
+			return bbLoss, nil // Checkmate.
+		}
+		return bbDraw, nil // Stalemate.
+	} else if !unresolved {
+		return bbLoss, nil
+	}
+
+	return bbUnknown, nil
+}
+
+// Marks unresolved positions that lead to the resolved one.
+func (bb *Bitbase) retract(index int, dirty []bool) {
+	setup, color := bb.setup(index)
+	board := setup.board()
+	squares := make([]int, setup.count)
+
+	for i := 0; i < setup.count; i++ {
+		piece, to := setup.pieces[i], setup.squares[i]
+		if piece.color() == color {
+			continue // The opponent has made the last move.
+		}
+
+		var sources Bitmask
+		if piece.isPawn() {
+			step := let(color == Black, 8, -8) // Opponent's pawn push.
+			if from := to - step; board.off(from) && row(from) != A1H1 && row(from) != A8H8 {
+				sources.set(from)
+				if row(to) == let(color == Black, A4H4, A5H5) && board.off(from - step) {
+					sources.set(from - step)
+				}
+			}
+		} else {
+			sources = bbAttacks(piece, to, board) & ^board
+		}
+
+		for sources.any() {
+			copy(squares, setup.squares[:setup.count])
+			squares[i] = sources.pop()
+			bb.normalize(squares)
+			if previous := bb.index(color ^ 1, squares); bb.values[previous] == bbUnknown {
+				dirty[previous] = true
+			}
+		}
+	}
+}
+
+// Generates the bitbase along with any missing bitbases it depends on.
+// Generated bitbases get registered for probing.
+func (bb *Bitbase) Generate() error {
+	bb.values, bb.data = make([]uint8, bb.size), nil
+	dirty := make([]bool, bb.size)
+
+	for index := 0; index < bb.size; index++ {
+		if bb.legal(index) {
+			bb.values[index], dirty[index] = bbUnknown, true
+		} else {
+			bb.values[index] = bbInvalid
+		}
+	}
+
+	// Keep resolving positions until no more progress can be made. After
+	// the first pass only the positions that lead to newly resolved ones
+	// need to be looked at again.
+	for pending := true; pending; {
+		pending = false
+		for index := 0; index < bb.size; index++ {
+			if !dirty[index] {
+				continue
+			}
+			dirty[index] = false
+			value, err := bb.evaluate(index)
+			if err != nil {
+				bb.values = nil
+				return err
+			}
+			if value != bbUnknown {
+				bb.values[index], pending = uint8(value), true
+				if value != bbDraw {
+					bb.retract(index, dirty)
+				}
+			}
+		}
+	}
+
+	// Unresolved positions are draws. Pack the values four per byte.
+	bb.data = make([]uint8, (bb.size + 3) / 4)
+	for index, value := range bb.values {
+		if value == bbWin || value == bbLoss {
+			bb.data[index >> 2] |= value << (uint(index & 3) << 1)
+		}
+	}
+	bb.values = nil
+
+	bb.register()
+
+	return nil
+}
+
+// Returns the number of wins, draws and losses for the side to move among
+// legal positions.
+func (bb *Bitbase) Stats() (wins, draws, losses int) {
+	for index := 0; index < bb.size; index++ {
+		if !bb.legal(index) {
+			continue
+		}
+		switch bb.value(index) {
+		case bbWin:
+			wins++
+		case bbLoss:
+			losses++
+		default:
+			draws++
+		}
+	}
+	return
+}
+
+// Saves the bitbase in the given directory.
+func (bb *Bitbase) Save(dir string) (string, error) {
+	buffer := bytes.NewBufferString(bbMagic)
+	buffer.WriteByte(byte(len(bb.name)))
+	buffer.WriteString(bb.name)
+	binary.Write(buffer, binary.LittleEndian, uint32(bb.size))
+
+	writer, _ := flate.NewWriter(buffer, flate.BestCompression)
+	writer.Write(bb.data)
+	writer.Close()
+
+	fileName := filepath.Join(dir, bb.name + bbExtension)
+	return fileName, ioutil.WriteFile(fileName, buffer.Bytes(), 0644)
+}
+
+// Loads bitbase from the file and registers it for probing.
+func LoadBitbase(fileName string) (*Bitbase, error) {
+	content, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if len(content) < len(bbMagic) + 1 || string(content[:len(bbMagic)]) != bbMagic {
+		return nil, fmt.Errorf("%s is not a bitbase file", fileName)
+	}
+
+	content = content[len(bbMagic):]
+	length := int(content[0])
+	if len(content) < 1 + length + 4 {
+		return nil, fmt.Errorf("%s is truncated", fileName)
+	}
+
+	bb, err := NewBitbase(string(content[1 : 1 + length]))
+	if err != nil {
+		return nil, err
+	}
+	if size := int(binary.LittleEndian.Uint32(content[1 + length:])); size != bb.size {
+		return nil, fmt.Errorf("%s has %d positions instead of %d", fileName, size, bb.size)
+	}
+
+	reader := flate.NewReader(bytes.NewReader(content[1 + length + 4:]))
+	defer reader.Close()
+	if bb.data, err = ioutil.ReadAll(reader); err != nil {
+		return nil, err
+	}
+	if len(bb.data) != (bb.size + 3) / 4 {
+		return nil, fmt.Errorf("%s is corrupted", fileName)
+	}
+
+	return bb.register(), nil
+}
+
+// Loads all bitbases found in the directory. Unreadable files are skipped.
+func LoadBitbases(dir string) (loaded int) {
+	if len(dir) == 0 {
+		return 0
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, `*` + bbExtension))
+	for _, fileName := range files {
+		if _, err := LoadBitbase(fileName); err == nil {
+			loaded++
+		}
+	}
+
+	return loaded
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `io/ioutil`; `os`; `testing`)
+
+// Generates the bitbase unless it's been generated already.
+func bbGenerate(t *testing.T, signature string) *Bitbase {
+	bb, err := NewBitbase(signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if existing := bitbases[bb.material]; existing != nil {
+		return existing
+	}
+	if err = bb.Generate(); err != nil {
+		t.Fatal(err)
+	}
+
+	return bb
+}
+
+// Material signatures.
+func TestBitbase000(t *testing.T) {
+	bb, err := NewBitbase(`KPKR`)
+	expect.True(t, err == nil)
+	expect.Eq(t, bb.name, `KRKP`)
+	expect.Eq(t, bb.pieces, []Piece{ King, Rook, BlackKing, BlackPawn })
+	expect.Eq(t, bb.size, 2 * 32 * 64 * 64 * 64)
+
+	bb, _ = NewBitbase(`KNKB`)
+	expect.Eq(t, bb.name, `KBKN`)
+	expect.Eq(t, bb.size, 2 * 10 * 64 * 64 * 64)
+
+	bb, _ = NewBitbase(`KNBK`)
+	expect.Eq(t, bb.name, `KBNK`)
+
+	_, err = NewBitbase(`KRPKR`)
+	expect.True(t, err != nil)
+	_, err = NewBitbase(`KXK`)
+	expect.True(t, err != nil)
+	_, err = NewBitbase(`RKK`)
+	expect.True(t, err != nil)
+}
+
+// Generated KPK matches the original bitbase.
+func TestBitbase010(t *testing.T) {
+	defer func() { bitbases = map[uint64]*Bitbase{} }()
+	bb := bbGenerate(t, `KPK`)
+
+	for index := 0; index < len(bitbase) * 64; index++ {
+		color, wKing, bKing, wPawn := index & 1, (index >> 1) & 0x3F, (index >> 7) & 0x3F, ((index >> 13) & 0x3F) + 8
+		if wKing == bKing || wKing == wPawn || bKing == wPawn || distance[wKing][bKing] <= 1 {
+			continue
+		}
+		if color == White && pawnAttacks[White][wPawn].on(bKing) {
+			continue
+		}
+
+		setup := BitbaseSetup{ count: 3, pieces: [bbPieces]Piece{ King, Pawn, BlackKing }, squares: [bbPieces]int{ wKing, wPawn, bKing } }
+		value := bb.probe(setup, uint8(color))
+		expected := let(bitbase[index / 64] & (1 << uint(index & 0x3F)) != 0, bbWin, bbDraw)
+		if color == Black && expected == bbWin {
+			expected = bbLoss
+		}
+		if value != expected {
+			t.Fatalf("KPK mismatch: color %d, king %d, king %d, pawn %d", color, wKing, bKing, wPawn)
+		}
+	}
+}
+
+// Rook and queen vs. bare king.
+func TestBitbase020(t *testing.T) {
+	defer func() { bitbases = map[uint64]*Bitbase{} }()
+	bbGenerate(t, `KRK`)
+
+	value, ok := NewGame(`Kc6,Rh1`, `Kc8`).start().probeBitbase()
+	expect.True(t, ok)
+	expect.Eq(t, value, bbWin)
+
+	value, _ = NewGame(`Kc6,Rh1`, `M,Kc8`).start().probeBitbase()
+	expect.Eq(t, value, bbLoss)
+
+	value, _ = NewGame(`Kc6,Rd8`, `M,Kc8`).start().probeBitbase() // Kc8xd8.
+	expect.Eq(t, value, bbDraw)
+
+	value, _ = NewGame(`Ke2`, `Kh8,Ra1`).start().probeBitbase() // Colors reversed.
+	expect.Eq(t, value, bbLoss)
+
+	_, ok = NewGame(`Ke2,Qd1`, `Kh8`).start().probeBitbase()
+	expect.False(t, ok)
+
+	bbGenerate(t, `KQK`)
+	value, _ = NewGame(`Kb6,Qc7`, `M,Ka8`).start().probeBitbase() // Stalemate.
+	expect.Eq(t, value, bbDraw)
+
+	value, _ = NewGame(`Kb6,Qc7`, `Ka8`).start().probeBitbase()
+	expect.Eq(t, value, bbWin)
+}
+
+// Save and load.
+func TestBitbase030(t *testing.T) {
+	defer func() { bitbases = map[uint64]*Bitbase{} }()
+	dir, err := ioutil.TempDir(``, `bitbase`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bb := bbGenerate(t, `KRK`)
+	fileName, err := bb.Save(dir)
+	expect.True(t, err == nil)
+
+	bitbases = map[uint64]*Bitbase{}
+	expect.Eq(t, LoadBitbases(dir), 1)
+
+	loaded := bitbases[bb.material]
+	expect.True(t, loaded != nil)
+	expect.Eq(t, loaded.name, `KRK`)
+	expect.Eq(t, loaded.data, bb.data)
+
+	ioutil.WriteFile(fileName, []byte(`DBB1`), 0644)
+	_, err = LoadBitbase(fileName)
+	expect.True(t, err != nil)
+}
+
+// Bitbase lookup from material table.
+func TestBitbase040(t *testing.T) {
+	defer func() { bitbases = map[uint64]*Bitbase{} }()
+
+	expect.True(t, materialBase[NewGame(`Kd1,Rh1`, `Ka8,a2`).start().balance].bitbase)
+	expect.False(t, materialBase[NewGame(`Ke1,Rh1,a2`, `Ke8,Ra8`).start().balance].bitbase)
+	expect.False(t, materialBase[NewGame(`Ke1,Bh1`, `Ke8`).start().balance].bitbase)
+
+	p := NewGame(`Kc6,Rd8`, `M,Kc8`).start()
+	expect.True(t, materialBase[p.balance].bitbase)
+	expect.Ne(t, p.Evaluate(), DrawScore)
+
+	bbGenerate(t, `KRK`)
+	expect.Eq(t, p.Evaluate(), DrawScore) // Kc8xd8.
+	expect.True(t, NewGame(`Kc6,Rh1`, `Kc8`).start().Evaluate() >= WhiteWinning)
+	expect.True(t, NewGame(`Kc6,Rh1`, `M,Kc8`).start().Evaluate() <= -WhiteWinning)
+	expect.True(t, NewGame(`Kc6`, `M,Kc8,Rh1`).start().Evaluate() >= WhiteWinning)
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (`sync`; `time`)
+
+// Time source behind the engine clock. The system time is used by default;
+// FakeTime lets tests run time management deterministically.
+type TimeSource interface {
+	Now() time.Time
+	Tick(interval time.Duration, callback func(now time.Time) bool) (stop func())
+	Poll()
+}
+
+// Wall clock time with the ticker running in its own goroutine.
+type systemTime struct{}
+
+func (systemTime) Now() time.Time {
+	return time.Now()
+}
+
+// Invokes the callback every interval until it returns true or the ticker
+// gets stopped.
+func (systemTime) Tick(interval time.Duration, callback func(now time.Time) bool) func() {
+	ticker, done, once := time.NewTicker(interval), make(chan bool), sync.Once{}
+
+	go func() {
+		for {
+			select {
+			case now := <-ticker.C:
+				if callback(now) {
+					ticker.Stop()
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+		})
+	}
+}
+
+func (systemTime) Poll() {
+}
+
+// Fake time that only moves forward when Advance() gets called, or as the
+// search goes on if the time per node is set. Ticker callbacks are invoked
+// synchronously from Advance().
+type FakeTime struct {
+	now      time.Time
+	perNode  time.Duration 		// Time it takes to search one node.
+	nodes    int 			// Node count the time was last advanced for.
+	tickers  []*fakeTicker
+}
+
+type fakeTicker struct {
+	interval  time.Duration
+	next      time.Time
+	callback  func(now time.Time) bool
+	stopped   bool
+}
+
+// Returns fake time source that advances by the given duration per node
+// searched. Zero duration means the time moves on explicit Advance() only.
+func NewFakeTime(perNode time.Duration) *FakeTime {
+	return &FakeTime{ now: time.Unix(0, 0), perNode: perNode }
+}
+
+func (ft *FakeTime) Now() time.Time {
+	return ft.now
+}
+
+func (ft *FakeTime) Tick(interval time.Duration, callback func(now time.Time) bool) func() {
+	ticker := &fakeTicker{ interval: interval, next: ft.now.Add(interval), callback: callback }
+	ft.tickers = append(ft.tickers, ticker)
+
+	return func() {
+		ticker.stopped = true
+	}
+}
+
+// Moves the time forward firing the ticks that fall within the duration in
+// chronological order.
+func (ft *FakeTime) Advance(duration time.Duration) {
+	target := ft.now.Add(duration)
+
+	for {
+		var earliest *fakeTicker
+		for _, ticker := range ft.tickers {
+			if !ticker.stopped && !ticker.next.After(target) && (earliest == nil || ticker.next.Before(earliest.next)) {
+				earliest = ticker
+			}
+		}
+		if earliest == nil {
+			break
+		}
+
+		ft.now = earliest.next
+		earliest.next = earliest.next.Add(earliest.interval)
+		if earliest.callback(ft.now) {
+			earliest.stopped = true
+		}
+	}
+
+	ft.now = target
+	ft.sweep()
+}
+
+// Advances the time for the nodes searched since the last poll.
+func (ft *FakeTime) Poll() {
+	if ft.perNode == 0 {
+		return
+	}
+
+	nodes := game.nodes + game.qnodes
+	if nodes < ft.nodes {
+		ft.nodes = 0 // Node counters have been reset for the new search.
+	}
+	if nodes > ft.nodes {
+		count := nodes - ft.nodes
+		ft.nodes = nodes
+		ft.Advance(ft.perNode * time.Duration(count))
+	}
+}
+
+// Drops stopped tickers.
+func (ft *FakeTime) sweep() {
+	active := ft.tickers[:0]
+	for _, ticker := range ft.tickers {
+		if !ticker.stopped {
+			active = append(active, ticker)
+		}
+	}
+	ft.tickers = active
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `os`; `testing`; `time`)
+
+// Plays the game against itself the way GUI would, i.e. passing remaining time
+// for the side to move, and returns time spent on each move in milliseconds.
+// Stops early if either side runs out of time.
+func simulateGame(timeLeft, timeInc, movesToGo int64, moves int, perNode time.Duration) (used []int64, clock [2]int64) {
+	defer func(saved Engine, stdout *os.File) { engine, os.Stdout = saved, stdout }(engine, os.Stdout)
+	if null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0); err == nil {
+		defer null.Close()
+		os.Stdout = null
+	}
+
+	fake := NewFakeTime(perNode)
+	engine.clock.source, engine.overhead = fake, 10
+	clock = [2]int64{ timeLeft, timeLeft }
+
+	p := NewGame().start()
+	for i := 0; i < moves; i++ {
+		color := p.color
+		togo := int64(0)
+		if movesToGo > 0 {
+			togo = movesToGo - int64(i / 2) % movesToGo
+		}
+
+		engine.varyingLimits(Options{ timeLeft: clock[color], timeInc: timeInc, movesToGo: togo })
+		start := fake.Now()
+		move := game.Think()
+		fake.Advance(time.Millisecond * time.Duration(engine.overhead)) // GUI lag.
+
+		spent := fake.Now().Sub(start).Nanoseconds() / 1000000
+		used = append(used, spent)
+		if clock[color] -= spent; clock[color] < 0 || move == Move(0) {
+			return
+		}
+		clock[color] += timeInc
+		if togo == 1 {
+			clock[color] += timeLeft // Next time control.
+		}
+
+		if p = p.makeMove(move); p.insufficient() || p.thirdRepetition() || p.fifty() || !NewGen(p, MaxPly).generateMoves().anyValid() {
+			return
+		}
+	}
+
+	return
+}
+
+// Fake time moves on explicit advance, and fires the ticks in order.
+func TestClock000(t *testing.T) {
+	fake, ticks := NewFakeTime(0), []int64{}
+	start := fake.Now()
+	stop := fake.Tick(100 * time.Millisecond, func(now time.Time) bool {
+		ticks = append(ticks, now.Sub(start).Nanoseconds() / 1000000)
+		return len(ticks) == 3
+	})
+
+	fake.Advance(250 * time.Millisecond)
+	expect.Eq(t, len(ticks), 2)
+	expect.Eq(t, ticks[1], int64(200))
+
+	fake.Advance(time.Second)
+	expect.Eq(t, len(ticks), 3) // Callback returned true.
+	expect.Eq(t, fake.Now().Sub(start), 1250 * time.Millisecond)
+
+	ticks = ticks[:0]
+	fake.Tick(100 * time.Millisecond, func(now time.Time) bool { ticks = append(ticks, 0); return false })
+	fake.Advance(100 * time.Millisecond)
+	stop()
+	expect.Eq(t, len(ticks), 1)
+}
+
+// Fixed time per move halts the search once the time is up.
+func TestClock010(t *testing.T) {
+	defer func(saved Engine) { engine = saved }(engine)
+
+	fake := NewFakeTime(0)
+	engine.clock.source, engine.overhead = fake, 0
+	engine.options = Options{ moveTime: 1000 }
+	game.rootpv.size = 1
+
+	engine.startClock()
+	fake.Advance(800 * time.Millisecond)
+	expect.False(t, engine.clock.halt)
+	fake.Advance(200 * time.Millisecond)
+	expect.True(t, engine.clock.halt)
+	engine.stopClock()
+}
+
+// Search time grows with the nodes searched.
+func TestClock020(t *testing.T) {
+	fake := NewFakeTime(time.Microsecond)
+	defer func(saved Engine) { engine = saved }(engine)
+	engine.clock.source = fake
+
+	game.nodes, game.qnodes = 500, 500
+	engine.poll()
+	expect.Eq(t, fake.Now().Sub(time.Unix(0, 0)), time.Millisecond)
+
+	game.nodes, game.qnodes = 100, 0 // New search.
+	engine.poll()
+	expect.Eq(t, fake.Now().Sub(time.Unix(0, 0)), 1100 * time.Microsecond)
+}
+
+// 40 moves in 10 seconds: no flag falls, and the time gets used up reasonably.
+func TestClock100(t *testing.T) {
+	used, clock := simulateGame(10000, 0, 40, 80, 20 * time.Microsecond)
+	expect.True(t, len(used) > 40)
+	expect.True(t, clock[White] > 0 && clock[Black] > 0)
+
+	total := int64(0)
+	for _, spent := range used {
+		expect.True(t, spent < 2500) // Never more than a quarter of the time control.
+		total += spent
+	}
+	expect.True(t, total / int64(len(used)) > 10000 / 40 / 2) // Shouldn't play too fast either.
+}
+
+// Sudden death with increment: the time left never drops below a couple of
+// increments.
+func TestClock110(t *testing.T) {
+	used, clock := simulateGame(3000, 100, 0, 120, 20 * time.Microsecond)
+	expect.True(t, len(used) > 40)
+	expect.True(t, clock[White] > 200 && clock[Black] > 200)
+}
@@ -13,13 +13,19 @@ import (
 
 // Ignore previous comment.
 func main() {
-	// Default engine settings are: 256MB transposition table, 5s per move.
+	// Default engine settings are: 256MB transposition table, 16MB evaluation
+	// cache, 5s per move.
 	engine := donna.NewEngine(
 		`fancy`, runtime.GOOS == `darwin`,
 		`cache`, 256,
+		`evalcache`, 16,
 		`movetime`, 5000,
 		`logfile`, os.Getenv(`DONNA_LOG`),
 		`bookfile`, os.Getenv(`DONNA_BOOK`),
+		`syzygy`, os.Getenv(`DONNA_SYZYGY`),
+		`bitbase`, os.Getenv(`DONNA_BITBASE`),
+		`params`, os.Getenv(`DONNA_PARAMS`),
+		`network`, os.Getenv(`DONNA_NETWORK`),
 	)
 
 	if len(os.Args) > 1 && os.Args[1] == `-i` {
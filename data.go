@@ -93,7 +93,14 @@ var castleRights = [64]uint8{
 	 7, 15, 15, 15,  3, 15, 15, 11,
 }
 
+// Initial squares of the king and the castle rooks (kingside and queenside)
+// for both sides. They only differ from the standard ones in Chess960 games,
+// see setupCastles().
+var castleKingFrom = [2]int{ E1, E8 }
+var castleRookFrom = [2][2]int{ { H1, A1 }, { H8, A8 } }
+
 var reMove = regexp.MustCompile(`([KQRBNEC]?)([a-h])([1-8])`)
+var reUciMove = regexp.MustCompile(`^[a-h][1-8][a-h][1-8][qrbn]?$`)
 
 var maskRank = [8]Bitmask{ // 0 to 8
 	0x00000000000000FF, 0x000000000000FF00, 0x0000000000FF0000, 0x00000000FF000000,
@@ -121,6 +128,11 @@ var homeTurf = [2]Bitmask{
 	0x000000003C3C3C00, 0x003C3C3C00000000, // 0x000000003c7e7e00, 0x007e7e3c00000000, ?!
 }
 
+// Queenside (A to D files) and kingside (E to H files).
+var maskWing = [2]Bitmask{
+	0x0F0F0F0F0F0F0F0F, 0xF0F0F0F0F0F0F0F0,
+}
+
 // Castle squares that should be *empty* in order for the castle to be valid.
 var gapKing = [2]Bitmask{
 	bit[F1]|bit[G1], bit[F8]|bit[G8],
@@ -27,6 +27,12 @@ var (
 	kingAttack     = Score{  2, 30 }  // Bonus for king attacking other pieces.
 	kingByPawn     = Score{  0,  8 }  // Penalty king being too far from friendly pawns.
 	pawnAlone      = Score{ 10,  5 }  // Penalty for unsupported pawn.
+	spaceSquare    = Score{  1,  0 }  // Bonus for safe center square behind pawns, scaled by number of pieces.
+	knightOutpost  = Score{ 16,  5 }  // Bonus for knight on square no enemy pawn can attack, doubled if supported.
+	bishopOutpost  = Score{  8,  2 }  // Bonus for bishop on square no enemy pawn can attack, doubled if supported.
+	rookConnected  = Score{ 10,  4 }  // Bonus for rooks defending each other.
+	queenBattery   = Score{ 15,  0 }  // Bonus for queen and bishop lined up against the king.
+	pawnMajority   = Score{  0, 10 }  // Bonus for healthy pawn majority on either wing.
 )
 
 // Weight percentages applied to evaluation scores before computing the overall
@@ -206,10 +212,17 @@ var bonusSemiPassedPawn = [8]Score{
 	{0, 0}, {3, 6}, {3, 6}, {7, 14}, {17, 34}, {41, 83}, {0, 0}, {0, 0},
 }
 
+var bonusCandidatePawn = [8]Score{
+	{0, 0}, {0, 4}, {0, 4}, {4, 10}, {8, 20}, {16, 40}, {0, 0}, {0, 0},
+}
+
 var extraPassedPawn = [8]int{
 	0, 0, 0, 1, 3, 6, 10, 0,
 }
 
+// Endgame score percentage when pawns are locked with no pawn breaks left.
+var lockedScale = 50
+
 var extraKnight = [64]int{
      //vvvvvvvvvvvv Black vvvvvvvvvvvv
 	0,  0,  0,  0,  0,  0,  0,  0,
@@ -236,6 +249,36 @@ var extraBishop = [64]int{
      //^^^^^^^^^^^^ White ^^^^^^^^^^^^
 }
 
+// Bonus for pushing bare king towards the edge of the board.
+var bonusBareKing = [64]int{
+      120, 100,  80,  60,  60,  80, 100, 120,
+      100,  80,  60,  40,  40,  60,  80, 100,
+       80,  60,  40,  20,  20,  40,  60,  80,
+       60,  40,  20,   0,   0,  20,  40,  60,
+       60,  40,  20,   0,   0,  20,  40,  60,
+       80,  60,  40,  20,  20,  40,  60,  80,
+      100,  80,  60,  40,  40,  60,  80, 100,
+      120, 100,  80,  60,  60,  80, 100, 120,
+}
+
+// Bonus for pushing bare king towards A1 or H8 corner where it could be
+// checkmated by knight and dark-squared bishop.
+var bonusCorner = [64]int{
+      840, 720, 600, 480, 360, 240, 120,   0,
+      720, 600, 480, 360, 240, 120,   0, 120,
+      600, 480, 360, 240, 120,   0, 120, 240,
+      480, 360, 240, 120,   0, 120, 240, 360,
+      360, 240, 120,   0, 120, 240, 360, 480,
+      240, 120,   0, 120, 240, 360, 480, 600,
+      120,   0, 120, 240, 360, 480, 600, 720,
+        0, 120, 240, 360, 480, 600, 720, 840,
+}
+
+// Bonus for bringing the kings closer, indexed by distance between them.
+var bonusCloser = [8]int{
+	0, 0, 100, 80, 60, 40, 20, 0,
+}
+
 // Non-hanging pawn attacking [1] Pawn, [2] Knight, [3] Bishop, [4] Rook, [5] Queen.
 var bonusPawnThreat = [6]Score{
 	{0, 0}, {0, 0}, {88, 69}, {65, 63}, {108, 109}, {101, 107},
@@ -256,8 +299,20 @@ var kingThreat = [6]int {
 	0, 0, 2, 2, 3, 5,
 }
 
-const queenCheck = 4
+// Safe check from [2] Knight, [3] Bishop, [4] Rook, [5] Queen, and queen
+// contact check supported by another piece.
+var safeCheck = [6]int {
+	0, 0, 1, 1, 1, 2,
+}
+var queenContact = 4
 
+// Enemy rooks and queens facing the king through [0] semi-open file with enemy
+// pawns only, and [1] open file.
+var kingFileDanger = [2]int {
+	1, 2,
+}
+
+// King danger curve indexed by the sum of attack units.
 var kingSafety = [64]int {
 	  0,   0,   1,   2,   3,   5,   7,  10,
 	 13,  16,  20,  24,  29,  34,  39,  45,
@@ -274,6 +329,11 @@ var penaltyCover = [7]int {
 	50, 0, 13, 36, 46, 50, 50,
 }
 
+// Storm penalty percentage for the files around the king: A/H, B/G, C/F, D/E.
+var stormFile = [4]int {
+	100, 100, 100, 100,
+}
+
 // Storming pawn with no frendly pawn stopping it, indexed by rank.
 var penaltyStorm = [8]int {
 	0, 32, 64, 25, 13, 0, 0, 0,
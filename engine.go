@@ -14,7 +14,8 @@ type Clock struct {
 	hardStop    int64    // Immediate stop time limit.
 	extra       float32  // Extra time factor based on search volatility.
 	start       time.Time
-	ticker      *time.Ticker
+	stop        func()      // Stops the ticker, nil if the clock isn't running.
+	source      TimeSource  // Time source, system time if nil.
 }
 
 type Options struct {
@@ -36,7 +37,23 @@ type Engine struct {
 	status      uint8    // Engine status.
 	logFile     string   // Log file name.
 	bookFile    string   // Polyglot opening book file name.
+	syzygyPath  string   // Syzygy tablebases directories.
+	bitbasePath string   // Endgame bitbases directory.
+	paramsFile  string   // Evaluation parameters file name.
+	networkFile string   // Neural network weights file name.
+	useNetwork  bool     // Use neural network instead of classic evaluation.
+	showWdl     bool     // Show win/draw/loss chances in UCI output.
+	analysis    bool     // Analysis mode: no contempt.
+	chess960    bool     // Chess960 castle notation: king takes rook.
+	contempt    int      // Draw score penalty in centipawns for the side to move at the root.
+	overhead    int64    // Move overhead in milliseconds to absorb GUI lag.
 	cacheSize   float64  // Default cache size.
+	evalCache   float64  // Evaluation cache size.
+	pawnSize    float64  // Pawn cache size.
+	pawnCache   *PawnCache // Pawn cache shared by all games.
+	pruneLate   bool     // Late move pruning of quiet moves.
+	pruneSee    bool     // Pruning of captures and quiet moves that lose material.
+	pruneGood   bool     // Pruning of quiet moves with bad history.
 	clock       Clock
 	options     Options
 }
@@ -45,7 +62,7 @@ type Engine struct {
 var engine Engine
 
 func NewEngine(args ...interface{}) *Engine {
-	engine = Engine{}
+	engine = Engine{ pruneLate: true, pruneSee: true, pruneGood: true, pawnSize: 2, evalCache: 16, overhead: 10 }
 	for i := 0; i < len(args); i += 2 {
 		switch value := args[i+1]; args[i] {
 		case `log`:
@@ -54,12 +71,47 @@ func NewEngine(args ...interface{}) *Engine {
 			engine.logFile = value.(string)
 		case `bookfile`:
 			engine.bookFile = value.(string)
+		case `syzygy`:
+			engine.syzygyPath = value.(string)
+		case `bitbase`:
+			engine.bitbasePath = value.(string)
+		case `params`:
+			engine.paramsFile = value.(string)
+		case `network`:
+			engine.networkFile = value.(string)
+			engine.useNetwork = len(engine.networkFile) > 0
+		case `overhead`:
+			engine.overhead = int64(value.(int))
+		case `clock`:
+			engine.clock.source = value.(TimeSource)
+		case `contempt`:
+			engine.contempt = value.(int)
+		case `analysis`:
+			engine.analysis = value.(bool)
+		case `wdl`:
+			engine.showWdl = value.(bool)
+		case `chess960`:
+			engine.chess960 = value.(bool)
 		case `uci`:
 			engine.uci = value.(bool)
 		case `trace`:
 			engine.trace = value.(bool)
 		case `fancy`:
 			engine.fancy = value.(bool)
+		case `pawncache`:
+			switch value.(type) {
+			default:
+				engine.pawnSize = value.(float64)
+			case int:
+				engine.pawnSize = float64(value.(int))
+			}
+		case `evalcache`:
+			switch value.(type) {
+			default:
+				engine.evalCache = value.(float64)
+			case int:
+				engine.evalCache = float64(value.(int))
+			}
 		case `depth`:
 			engine.options.maxDepth = value.(int)
 		case `movetime`:
@@ -73,6 +125,18 @@ func NewEngine(args ...interface{}) *Engine {
 			}
 		}
 	}
+	NewTablebase(engine.syzygyPath)
+	LoadBitbases(engine.bitbasePath)
+	if len(engine.paramsFile) > 0 {
+		if err := LoadParameters(engine.paramsFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading parameters: %v\n", err)
+			engine.paramsFile = ``
+		}
+	}
+	if err := LoadNetwork(engine.networkFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading network: %v\n", err)
+		engine.networkFile, engine.useNetwork = ``, false
+	}
 
 	return &engine
 }
@@ -126,6 +190,30 @@ func (e *Engine) varyingTime() bool {
 }
 
 
+// Returns current time as reported by the clock's time source.
+func (e *Engine) now() time.Time {
+	return e.timeSource().Now()
+}
+
+// Returns time in milliseconds elapsed since the given start time.
+func (e *Engine) since(start time.Time) int64 {
+	return e.now().Sub(start).Nanoseconds() / 1000000
+}
+
+// Lets fake time source catch up with the search, no-op for the system time.
+func (e *Engine) poll() {
+	if e.clock.source != nil {
+		e.clock.source.Poll()
+	}
+}
+
+func (e *Engine) timeSource() TimeSource {
+	if e.clock.source == nil {
+		return systemTime{}
+	}
+	return e.clock.source
+}
+
 // Returns elapsed time in milliseconds.
 func (e *Engine) elapsed(now time.Time) int64 {
 	return now.Sub(e.clock.start).Nanoseconds() / 1000000 //int64(time.Millisecond)
@@ -138,11 +226,19 @@ func (e *Engine) remaining() int64 {
 }
 
 // Sets extra time factor. For depths 5+ we take into account search volatility,
-// i.e. extra time is given for uncertain positions where the best move is not clear.
+// i.e. extra time is given for uncertain positions where the best move is not clear,
+// and when root score drops. Easy moves that stay best for several iterations
+// get less time.
 func (e *Engine) factor(depth int, volatility float32) *Engine {
 	e.clock.extra = 0.75
 	if depth >= 5 {
 		e.clock.extra *= (volatility + 1.0)
+		if game.drop > 0 {
+			e.clock.extra *= 1.0 + float32(min(game.drop, onePawn)) / float32(onePawn)
+		}
+		if game.stability >= 4 {
+			e.clock.extra *= 0.6
+		}
 	}
 
 	return e
@@ -157,8 +253,7 @@ func (e *Engine) startClock() *Engine {
 		return e
 	}
 
-	e.clock.start = time.Now()
-	e.clock.ticker = time.NewTicker(time.Millisecond * Ping)
+	e.clock.start = e.now()
 
 	if e.fixedTime() {
 		return e.fixedTimeTicker()
@@ -170,9 +265,9 @@ func (e *Engine) startClock() *Engine {
 
 // Stop the clock so that the ticker callback function is longer invoked.
 func (e *Engine) stopClock() *Engine {
-	if e.clock.ticker != nil {
-		e.clock.ticker.Stop()
-		e.clock.ticker = nil
+	if e.clock.stop != nil {
+		e.clock.stop()
+		e.clock.stop = nil
 	}
 	return e
 }
@@ -180,20 +275,16 @@ func (e *Engine) stopClock() *Engine {
 // Ticker callback for fixed time control (ex. 5s per move). Search gets terminated
 // when we've got the move and the elapsed time approaches time-per-move limit.
 func (e *Engine) fixedTimeTicker() *Engine {
-	go func() {
-		if e.clock.ticker == nil {
-			return // Nothing to do if the clock has been stopped.
+	e.clock.stop = e.timeSource().Tick(time.Millisecond * Ping, func(now time.Time) bool {
+		if game.rootpv.size == 0 {
+			return false // Haven't found the move yet.
 		}
-		for now := range e.clock.ticker.C {
-			if game.rootpv.size == 0 {
-				continue // Haven't found the move yet.
-			}
-			if e.elapsed(now) >= e.options.moveTime - Ping {
-				e.clock.halt = true
-				return
-			}
+		if e.elapsed(now) >= e.options.moveTime - Ping - e.overhead {
+			e.clock.halt = true
+			return true
 		}
-	}()
+		return false
+	})
 
 	return e
 }
@@ -201,23 +292,19 @@ func (e *Engine) fixedTimeTicker() *Engine {
 // Ticker callback for the variable time control (ex. 40 moves in 5 minutes). Search
 // termination depends on multiple factors with hard stop being the ultimate limit.
 func (e *Engine) varyingTimeTicker() *Engine {
-	go func() {
-		if e.clock.ticker == nil {
-			return // Nothing to do if the clock has been stopped.
+	e.clock.stop = e.timeSource().Tick(time.Millisecond * Ping, func(now time.Time) bool {
+		if game.rootpv.size == 0 {
+			return false // Haven't found the move yet.
 		}
-		for now := range e.clock.ticker.C {
-			if game.rootpv.size == 0 {
-				continue // Haven't found the move yet.
-			}
-			elapsed := e.elapsed(now)
-			if (game.deepening && game.improving && elapsed > e.remaining() * 4 / 5) || elapsed > e.clock.hardStop {
-				//\\ e.debug("# Halt: Flags %v Elapsed %s Remaining %s Hard stop %s\n",
-				//\\	game.deepening && game.improving, ms(elapsed), ms(e.remaining() * 4 / 5), ms(e.clock.hardStop))
-				e.clock.halt = true
-				return
-			}
+		elapsed := e.elapsed(now)
+		if (game.deepening && game.improving && elapsed > e.remaining() * 4 / 5) || elapsed > e.clock.hardStop {
+			//\\ e.debug("# Halt: Flags %v Elapsed %s Remaining %s Hard stop %s\n",
+			//\\	game.deepening && game.improving, ms(elapsed), ms(e.remaining() * 4 / 5), ms(e.clock.hardStop))
+			e.clock.halt = true
+			return true
 		}
-	}()
+		return false
+	})
 
 	return e
 }
@@ -239,15 +326,17 @@ func (e *Engine) varyingLimits(options Options) *Engine {
 	e.options.maxNodes = 0
 	e.options.moveTime = 0
 
-	// Set default number of moves till the end of the game or time control.
-	// TODO: calculate based on game phase.
-	if e.options.movesToGo == 0 {
-		e.options.movesToGo = 40
+	// Estimate number of moves till the end of the game unless time control
+	// gets there sooner.
+	if estimate := movesLeft(game.position()); e.options.movesToGo == 0 || e.options.movesToGo > estimate {
+		e.options.movesToGo = estimate
 	}
 
-	// Calculate hard and soft stop estimates.
+	// Calculate hard and soft stop estimates leaving move overhead for each
+	// of the remaining moves. Since the ticker can't stop the search sooner
+	// than one ping cycle every remaining move also needs the ping reserve.
 	moves := e.options.movesToGo - 1
-	hard := options.timeLeft + options.timeInc * moves
+	hard := max64(options.timeLeft / 10, options.timeLeft + options.timeInc * moves - e.overhead * e.options.movesToGo - Ping * moves)
 	soft := hard / e.options.movesToGo
 
 	//\\ e.debug("#\n# Make %d moves in %s soft stop %s hard stop %s\n", e.options.movesToGo, ms(e.options.timeLeft), ms(soft), ms(hard))
@@ -275,8 +364,9 @@ func (e *Engine) varyingLimits(options Options) *Engine {
 		e.clock.softStop, e.clock.hardStop = hard, soft
 	}
 
-	// Keep two ping cycles available to avoid accidental time forefeit.
-	e.clock.hardStop -= 2 * Ping
+	// Keep two ping cycles and move overhead available to avoid accidental
+	// time forefeit.
+	e.clock.hardStop -= 2 * Ping + e.overhead
 	if e.clock.hardStop < 0 {
 		e.clock.hardStop = options.timeLeft // Oh well...
 	}
@@ -285,3 +375,11 @@ func (e *Engine) varyingLimits(options Options) *Engine {
 
 	return e
 }
+
+// Estimates number of moves till the end of the game: about 45 moves with all
+// the pieces on the board and 20 moves in pawn endgames. The estimate goes
+// down as the game goes on but never drops below 12 moves.
+func movesLeft(p *Position) int64 {
+	phase := min(materialBase[p.balance].phase, 256)
+	return int64(max(12, 20 + 25 * phase / 256 - min(game.ply(), 160) / 16))
+}
@@ -7,6 +7,8 @@ package donna
 import(
 	`fmt`
 	`io/ioutil`
+	`os`
+	`path/filepath`
 	`regexp`
 	`runtime`
 	`strconv`
@@ -25,6 +27,8 @@ func (e *Engine) replBestMove(move Move) *Engine {
 	fmt.Printf(ansiTeal + "Donna's move: %s", move)
 	if game.nodes == 0 {
 		fmt.Printf(" (book)")
+	} else if stats := cacheStats(); stats != `` {
+		fmt.Printf(" (%s)", stats)
 	}
 	fmt.Println(ansiNone + "\n")
 
@@ -47,7 +51,8 @@ func (e *Engine) replPrincipal(depth, score, status int, duration int64) {
 	case WhiteWinning, BlackWinning: // Show moves till checkmate.
 		fmt.Printf("%6dX   %v Checkmate\n", (Checkmate - abs(score)) / 2 + 1, game.rootpv.moves[0:game.rootpv.size])
 	default:
-		fmt.Printf("%7.2f   %v\n", float32(score) / float32(onePawn), game.rootpv.moves[0:game.rootpv.size])
+		win, draw, loss := game.wdl(score)
+		fmt.Printf("%7.2f %3d%% %3d%% %3d%%   %v\n", float32(score) / float32(onePawn), (win + 5) / 10, (draw + 5) / 10, (loss + 5) / 10, game.rootpv.moves[0:game.rootpv.size])
 	}
 }
 
@@ -62,17 +67,25 @@ func (e *Engine) Repl() *Engine {
 		ansiRed, ansiGreen, ansiTeal, ansiNone = ``, ``, ``, ``
 	}
 
-	setup := func() {
+	// Starts new game unless it's in progress. Returns false if the initial
+	// position could not be set up.
+	setup := func() bool {
 		if game == nil || position == nil {
+			var err error
 			game = NewGame()
-			position = game.start()
+			if position, err = game.setup(); err != nil {
+				fmt.Printf("Error setting up position: %v\n", err)
+				game, position = nil, nil
+				return false
+			}
 			fmt.Printf("%s\n", position)
 		}
+		return true
 	}
 
 	think := func() {
 		if move := game.Think(); move != 0 {
-			position = position.makeMove(move)
+			position = game.makeMove(move)
 			fmt.Printf("%s\n", position)
 		}
 	}
@@ -85,6 +98,61 @@ func (e *Engine) Repl() *Engine {
 		}
 	}
 
+	params := func(fileName string) {
+		if fileName == `dump` {
+			writeParameters(os.Stdout, evaluationParameters())
+		} else if err := LoadParameters(fileName); err != nil {
+			fmt.Printf("Error loading parameters: %v\n", err)
+		} else if e.paramsFile, game, position = fileName, nil, nil; fileName == `` {
+			fmt.Println(`Using default evaluation parameters`)
+		} else {
+			fmt.Printf("Using evaluation parameters from %s\n", fileName)
+		}
+	}
+
+	symmetric := func(fileName string) {
+		if fileName == `` {
+			if !setup() {
+				return
+			}
+			if diff := symmetry(position.fen()); len(diff) > 0 {
+				fmt.Printf("%s\n", strings.Join(diff, "\n"))
+			} else {
+				fmt.Println(`Evaluation is symmetric`)
+			}
+		} else if count, err := symmetryCheck(fileName); err != nil {
+			fmt.Printf("Error checking symmetry: %v\n", err)
+		} else {
+			fmt.Printf("Asymmetric positions: %d\n", count)
+		}
+		game, position = nil, nil
+	}
+
+	bitbase := func(signature string) {
+		bb, err := NewBitbase(signature)
+		if err == nil {
+			fmt.Printf("Generating %s bitbase...\n", bb.name)
+			start := time.Now()
+			if err = bb.Generate(); err == nil {
+				wins, draws, losses := bb.Stats()
+				fmt.Printf("Elapsed: %s\n", ms(since(start)))
+				fmt.Printf("   Wins: %d\n  Draws: %d\n Losses: %d\n", wins, draws, losses)
+
+				dir := e.bitbasePath
+				if dir == `` {
+					dir = `.`
+				}
+				var fileName string
+				if fileName, err = bb.Save(dir); err == nil {
+					fmt.Printf("Saved %s\n", fileName)
+				}
+			}
+		}
+		if err != nil {
+			fmt.Printf("Error generating bitbase: %v\n", err)
+		}
+	}
+
 	benchmark := func(fileName string) {
 		maxDepth, moveTime := e.options.maxDepth, e.options.moveTime
 		e.options.maxDepth, e.options.moveTime = 0, 10000
@@ -104,10 +172,20 @@ func (e *Engine) Repl() *Engine {
 			for _, line := range strings.Split(string(content), "\n") {
 				if len(line) > 0 && line[0] != '#' {
 					total++
-					game := NewGame(line)
-					position := game.start()
+					fields := strings.Split(line, ` # `)
+					if len(fields) != 2 {
+						fmt.Printf(ansiRed + "%d) Missing best move: %s\n\n\n" + ansiNone, total, line)
+						continue
+					}
 
-					best := strings.Split(line, ` # `)[1] // TODO: add support for "am" (avoid move).
+					game := NewGame(fields[0])
+					position, err := game.setup()
+					if err != nil {
+						fmt.Printf(ansiRed + "%d) Invalid position: %v\n\n\n" + ansiNone, total, err)
+						continue
+					}
+
+					best := fields[1] // TODO: add support for "am" (avoid move).
 					fmt.Printf(ansiTeal + "%d) %s for %s" + ansiNone + "\n%s\n", total, best, C(position.color), position)
 					move := game.Think()
 
@@ -126,6 +204,27 @@ func (e *Engine) Repl() *Engine {
 		}
 	}
 
+	tune := func(fileName string) {
+		tuner, err := NewTuner(fileName)
+		if err == nil {
+			paramsFile := strings.TrimSuffix(fileName, filepath.Ext(fileName)) + `.params`
+			if _, err = tuner.Tune(paramsFile); err == nil {
+				fmt.Printf("Saved %s\n", paramsFile)
+			}
+		}
+		if err != nil {
+			fmt.Printf("Error tuning evaluation: %v\n", err)
+		}
+		game, position = nil, nil // Make sure the game gets restarted.
+	}
+
+	fitWdl := func(fileName string) {
+		if err := FitWdl(fileName); err != nil {
+			fmt.Printf("Error fitting win/draw/loss model: %v\n", err)
+		}
+		game, position = nil, nil // Make sure the game gets restarted.
+	}
+
 	perft := func(parameter string) {
 		if parameter == `` {
 			parameter = `5`
@@ -151,43 +250,69 @@ func (e *Engine) Repl() *Engine {
 		case ``:
 		case `bench`:
 			benchmark(parameter)
+		case `bitbase`:
+			bitbase(parameter)
 		case `book`:
 			book(parameter)
 		case `exit`, `quit`:
 			return e
 		case `go`:
-			setup()
-			think()
+			if setup() {
+				think()
+			}
 		case `help`, `?`:
-			fmt.Println("The commands are:\n\n" +
+			fmt.Print("The commands are:\n\n" +
 				"  bench <file>   Run benchmarks\n" +
+				"  bitbase <KRKP> Generate endgame bitbase\n" +
 				"  book <file>    Use opening book\n" +
 				"  exit           Exit the program\n" +
 				"  go             Take side and make a move\n" +
 				"  help           Display this help\n" +
 				"  new            Start new game\n" +
+				"  params <file>  Load evaluation parameters (or dump)\n" +
 				"  perft [depth]  Run perft test\n" +
-				"  score          Show evaluation summary\n" +
-				"  undo           Undo last move\n\n" +
-				"To make a move use algebraic notation, for example e2e4, Ng1f3, or e7e8Q\n")
+				"  score [json]   Show evaluation summary\n" +
+				"  symmetry       Check evaluation symmetry (or EPD file)\n" +
+				"  tune <file>    Tune evaluation using labeled positions\n" +
+				"  undo           Undo last move\n" +
+				"  wdl <file>     Fit win/draw/loss model to PGN games\n\n" +
+				"To make a move use algebraic notation, for example e2e4, Ng1f3, or e7e8Q\n\n")
 		case `new`:
 			game, position = nil, nil
 			setup()
+		case `params`:
+			params(parameter)
 		case `perft`:
 			perft(parameter)
+		case `symmetry`:
+			symmetric(parameter)
+		case `tune`:
+			tune(parameter)
 		case `score`:
-			setup()
-			_, metrics := position.EvaluateWithTrace()
-			Summary(metrics)
+			if !setup() {
+				break
+			}
+			if parameter == `json` {
+				if data, err := position.Trace().JSON(); err == nil {
+					fmt.Printf("%s\n", data)
+				}
+			} else {
+				_, metrics := position.EvaluateWithTrace()
+				Summary(metrics)
+			}
+		case `wdl`:
+			fitWdl(parameter)
 		case `undo`:
 			if position != nil {
 				position = position.undoLastMove()
 				fmt.Printf("%s\n", position)
 			}
 		default:
-			setup()
+			if !setup() {
+				break
+			}
 			if move, validMoves := NewMoveFromString(position, command); move != 0 {
-				position = position.makeMove(move)
+				position = game.makeMove(move)
 				think()
 			} else { // Invalid move or non-evasion on check.
 				fancy := e.fancy; e.fancy = false
@@ -196,5 +321,4 @@ func (e *Engine) Repl() *Engine {
 			}
 		}
 	}
-	return e
 }
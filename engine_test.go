@@ -0,0 +1,68 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `testing`)
+
+// Moves left estimate depends on material and move number.
+func TestEngine000(t *testing.T) {
+	opening := movesLeft(NewGame().start())
+	endgame := movesLeft(NewGame(`Ke1,a2,b2`, `Ke8,a7,b7`).start())
+	expect.Eq(t, opening, int64(45))
+	expect.Eq(t, endgame, int64(20))
+
+	p := NewGame().start()
+	defer func() { node = 0 }()
+	node = 80 // 40 moves into the game.
+	expect.Eq(t, movesLeft(p), int64(40))
+
+	p = NewGame(`Ke1,a2`, `Ke8,h7`).start()
+	node = 160 // Long endgame.
+	expect.Eq(t, movesLeft(p), int64(12))
+}
+
+// Move overhead and game phase shape time allocation.
+func TestEngine010(t *testing.T) {
+	defer func(saved Engine) { engine = saved }(engine)
+
+	NewGame().start()
+	engine.overhead = 0
+	engine.varyingLimits(Options{ timeLeft: 60000 })
+	opening, hard := engine.clock.softStop, engine.clock.hardStop
+	expect.Eq(t, engine.options.movesToGo, int64(45))
+
+	engine.overhead = 100
+	engine.varyingLimits(Options{ timeLeft: 60000 })
+	expect.True(t, engine.clock.softStop < opening)
+	expect.True(t, engine.clock.hardStop < hard)
+
+	// Time control with fewer moves to go than estimated.
+	engine.varyingLimits(Options{ timeLeft: 60000, movesToGo: 10 })
+	expect.Eq(t, engine.options.movesToGo, int64(10))
+
+	// More time per move in the endgame.
+	NewGame(`Ke1,a2,b2`, `Ke8,a7,b7`).start()
+	engine.overhead = 0
+	engine.varyingLimits(Options{ timeLeft: 60000 })
+	expect.True(t, engine.clock.softStop > opening)
+}
+
+// Extra time on root score drop, and less time for easy moves.
+func TestEngine020(t *testing.T) {
+	defer func(saved Engine) { engine = saved }(engine)
+
+	NewGame().start()
+	game.getReady()
+	base := engine.factor(10, 0.0).clock.extra
+	expect.Eq(t, engine.factor(4, 0.0).clock.extra, base)
+
+	game.drop = onePawn / 2
+	expect.Eq(t, engine.factor(10, 0.0).clock.extra, base * 1.5)
+	game.drop = onePawn * 5
+	expect.Eq(t, engine.factor(10, 0.0).clock.extra, base * 2.0)
+
+	game.drop, game.stability = 0, 4
+	expect.True(t, engine.factor(10, 0.0).clock.extra < base)
+}
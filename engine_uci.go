@@ -39,7 +39,10 @@ func (e *Engine) uciMove(move Move, moveno, depth int) *Engine {
 }
 
 func (e *Engine) uciBestMove(move Move, duration int64) *Engine {
-	return engine.reply("info nodes %d time %d\nbestmove %s\n", game.nodes + game.qnodes, duration, move.notation())
+	if stats := cacheStats(); stats != `` {
+		engine.reply("info string %s\n", stats)
+	}
+	return engine.reply("info nodes %d tbhits %d time %d\nbestmove %s\n", game.nodes + game.qnodes, game.tbhits, duration, move.notation())
 }
 
 func (e *Engine) uciPrincipal(depth, score int, duration int64) *Engine {
@@ -54,7 +57,11 @@ func (e *Engine) uciPrincipal(depth, score int, duration int64) *Engine {
 		}
 		str += fmt.Sprintf(" mate %d", mate / 2)
 	}
-	str += fmt.Sprintf(" nodes %d nps %d time %d pv", game.nodes + game.qnodes, nps(duration), duration)
+	if e.showWdl {
+		win, draw, loss := game.wdl(score)
+		str += fmt.Sprintf(" wdl %d %d %d", win, draw, loss)
+	}
+	str += fmt.Sprintf(" nodes %d nps %d tbhits %d time %d pv", game.nodes + game.qnodes, nps(duration), game.tbhits, duration)
 
 	for i := 0; i < game.rootpv.size; i++ {
 		str += " " + game.rootpv.moves[i].notation()
@@ -77,6 +84,36 @@ func (e *Engine) Uci() *Engine {
 		e.reply("id name Donna %s\n", Version)
 		e.reply("id author Michael Dvorkin\n")
 		e.reply("option name Hash type spin default 256 min 32 max 1024\n")
+		e.reply("option name EvalHash type spin default 16 min 0 max 256\n")
+		e.reply("option name PawnHash type spin default 2 min 1 max 64\n")
+		e.reply("option name LateMovePruning type check default true\n")
+		e.reply("option name ExchangePruning type check default true\n")
+		e.reply("option name HistoryPruning type check default true\n")
+		e.reply("option name UCI_ShowWDL type check default false\n")
+		e.reply("option name UCI_AnalyseMode type check default false\n")
+		e.reply("option name UCI_Chess960 type check default false\n")
+		e.reply("option name Contempt type spin default 0 min -100 max 100\n")
+		e.reply("option name Move Overhead type spin default 10 min 0 max 5000\n")
+		if len(e.syzygyPath) > 0 {
+			e.reply("option name SyzygyPath type string default %s\n", e.syzygyPath)
+		} else {
+			e.reply("option name SyzygyPath type string default <empty>\n")
+		}
+		if len(e.paramsFile) > 0 {
+			e.reply("option name ParamsFile type string default %s\n", e.paramsFile)
+		} else {
+			e.reply("option name ParamsFile type string default <empty>\n")
+		}
+		if len(e.networkFile) > 0 {
+			e.reply("option name NetworkFile type string default %s\n", e.networkFile)
+		} else {
+			e.reply("option name NetworkFile type string default <empty>\n")
+		}
+		if e.useNetwork {
+			e.reply("option name Evaluation type combo default Network var Classic var Network\n")
+		} else {
+			e.reply("option name Evaluation type combo default Classic var Classic var Network\n")
+		}
 		// e.reply("option name Mobility type spin default %d min 0 max 100\n", weightMobility.midgame)
 		// e.reply("option name PawnStructure type spin default %d min 0 max 100\n", weightPawnStructure.midgame)
 		// e.reply("option name PassedPawns type spin default %d min 0 max 100\n", weightPassedPawns.midgame)
@@ -101,6 +138,9 @@ func (e *Engine) Uci() *Engine {
 		if game == nil || position == nil {
 			game = NewGame()
 		}
+		if len(args) == 0 {
+			return
+		}
 
 		switch args[0] {
 		case `startpos`:
@@ -116,21 +156,37 @@ func (e *Engine) Uci() *Engine {
 				fen = append(fen, token)
 			}
 			game.initial = strings.Join(fen, ` `)
-			position = game.start()
+			if p, err := game.setup(); err != nil {
+				e.reply("info string %v\n", err)
+				position = nil
+			} else {
+				position = p
+			}
 		default:
 			return
 		}
 
+		// Stop at the first illegal move keeping the last valid position.
 		if position != nil && len(args) > 0 && args[0] == `moves` {
-			for _, move := range args[1:] {
-				args = args[1:] // Shift the move.
-				position = position.makeMove(NewMoveFromNotation(position, move))
+			for _, notation := range args[1:] {
+				move := NewMoveFromUci(position, notation)
+				if move == Move(0) {
+					e.reply("info string illegal move %s\n", notation)
+					break
+				}
+				position = game.makeMove(move)
 			}
 		}
 	}
 
 	// "go [[wtime winc | btime binc ] movestogo] | depth | nodes | movetime"
 	doGo := func(args []string) {
+		// Give up the move if there is no valid position to think about.
+		if position == nil {
+			e.reply("bestmove 0000\n")
+			return
+		}
+
 		think := true
 		options := e.options
 
@@ -205,13 +261,82 @@ func (e *Engine) Uci() *Engine {
 		e.clock.halt = true
 	}
 
-	// Set UCI option. So far we only support "setoption name Hash value 32..1024".
+	// Set UCI option: "setoption name <id> [value <x>]". Option names and
+	// values might contain spaces.
 	doSetOption := func(args []string) {
-		if len(args) == 4 && args[0] == `name` && args[1] == `Hash` && args[2] == `value` {
-			if n, err := strconv.Atoi(args[3]); err == nil && n >= 32 && n <= 1024 {
+		name, value := strings.Join(args, ` `), ``
+		if pair := strings.SplitN(name, ` value `, 2); len(pair) == 2 {
+			name, value = pair[0], pair[1]
+		}
+
+		switch strings.TrimPrefix(name, `name `) {
+		case `Hash`:
+			if n, err := strconv.Atoi(value); err == nil && n >= 32 && n <= 1024 {
 				e.cacheSize = float64(n)
 				game, position = nil, nil // Make sure the game gets restarted.
 			}
+		case `EvalHash`:
+			if n, err := strconv.Atoi(value); err == nil && n >= 0 && n <= 256 {
+				e.evalCache = float64(n)
+				game, position = nil, nil // Make sure the game gets restarted.
+			}
+		case `PawnHash`:
+			if n, err := strconv.Atoi(value); err == nil && n >= 1 && n <= 64 {
+				e.pawnSize = float64(n)
+				game, position = nil, nil // Make sure the game gets restarted.
+			}
+		case `LateMovePruning`:
+			e.pruneLate = (value == `true`)
+		case `ExchangePruning`:
+			e.pruneSee = (value == `true`)
+		case `HistoryPruning`:
+			e.pruneGood = (value == `true`)
+		case `UCI_ShowWDL`:
+			e.showWdl = (value == `true`)
+		case `UCI_AnalyseMode`:
+			e.analysis = (value == `true`)
+		case `UCI_Chess960`:
+			e.chess960 = (value == `true`)
+		case `Move Overhead`:
+			if n, err := strconv.Atoi(value); err == nil && n >= 0 && n <= 5000 {
+				e.overhead = int64(n)
+			}
+		case `Contempt`:
+			if n, err := strconv.Atoi(value); err == nil && n >= -100 && n <= 100 {
+				e.contempt = n
+			}
+		case `SyzygyPath`:
+			if value == `<empty>` {
+				value = ``
+			}
+			e.syzygyPath = value
+			NewTablebase(value)
+		case `ParamsFile`:
+			if value == `<empty>` {
+				value = ``
+			}
+			if err := LoadParameters(value); err != nil {
+				e.reply("info string %v\n", err)
+			} else {
+				e.paramsFile = value
+				game, position = nil, nil // Cached evaluations are stale now.
+			}
+		case `NetworkFile`:
+			if value == `<empty>` {
+				value = ``
+			}
+			if err := LoadNetwork(value); err != nil {
+				e.reply("info string %v\n", err)
+			} else {
+				e.networkFile = value
+				game, position = nil, nil // Accumulators need to be refreshed.
+			}
+		case `Evaluation`:
+			if e.useNetwork = (value == `Network`); e.useNetwork && network == nil {
+				e.useNetwork = false
+				e.reply("info string NetworkFile is not set\n")
+			}
+			game, position = nil, nil
 		}
 	}
 
@@ -0,0 +1,119 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `io/ioutil`; `os`; `strings`; `testing`)
+
+// Feeds the commands to UCI loop and returns engine's replies.
+func uci(commands ...string) string {
+	defer func(saved Engine, stdin, stdout *os.File) {
+		engine, os.Stdin, os.Stdout = saved, stdin, stdout
+	}(engine, os.Stdin, os.Stdout)
+
+	input, commander, _ := os.Pipe()
+	listener, output, _ := os.Pipe()
+	commander.WriteString(strings.Join(append(commands, `quit`), "\n") + "\n")
+	commander.Close()
+
+	os.Stdin, os.Stdout = input, output
+	engine.Uci()
+	output.Close()
+
+	replies, _ := ioutil.ReadAll(listener)
+	return string(replies)
+}
+
+// Valid moves get applied.
+func TestUci000(t *testing.T) {
+	replies := uci(`position startpos moves e2e4 e7e5 g1f3 b8c6 f1c4 g8f6 e1g1`)
+	p := game.position()
+	expect.Eq(t, replies, ``)
+	expect.Eq(t, node, 7)
+	expect.Eq(t, p.fen(), `r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQ1RK1 b kq - 5 1`)
+}
+
+// Illegal move stops the move list keeping the last valid position.
+func TestUci010(t *testing.T) {
+	replies := uci(`position startpos moves e2e4 e7e5 e1g1 g1f3`)
+	expect.Eq(t, replies, "info string illegal move e1g1\n")
+	expect.Eq(t, node, 2)
+	expect.Eq(t, game.position().fen(), `rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 1`)
+}
+
+// Malformed moves, moves of the wrong side, and missing promotions.
+func TestUci020(t *testing.T) {
+	expect.Eq(t, uci(`position startpos moves e2e5`), "info string illegal move e2e5\n")
+	expect.Eq(t, uci(`position startpos moves e7e5`), "info string illegal move e7e5\n")
+	expect.Eq(t, uci(`position startpos moves e2-e4`), "info string illegal move e2-e4\n")
+	expect.Eq(t, uci(`position startpos moves e2e4 x`), "info string illegal move x\n")
+	expect.Eq(t, node, 1)
+
+	fen := `position fen 4k3/1P6/8/8/8/8/8/4K3 w - - 0 1 moves `
+	expect.Eq(t, uci(fen + `b7b8`), "info string illegal move b7b8\n")
+	expect.Eq(t, uci(fen + `b7b8k`), "info string illegal move b7b8k\n")
+	expect.Eq(t, uci(fen + `b7b8n`), ``)
+	expect.Eq(t, game.position().pieces[B8], Piece(Knight))
+}
+
+// Invalid position is reported, and there is no move to make.
+func TestUci030(t *testing.T) {
+	replies := uci(`position fen 4r1k1/8/8/8/8/8/8/4K3 b - - 0 1`, `go depth 1`)
+	expect.Contain(t, replies, `info string side not to move is in check`)
+	expect.Contain(t, replies, `bestmove 0000`)
+	expect.Eq(t, uci(`position`), ``)
+}
+
+// Move list longer than the search tree depth.
+func TestUci035(t *testing.T) {
+	replies := uci(`position startpos moves` + strings.Repeat(` g1f3 g8f6 f3g1 f6g8`, 20) + ` e2e4`)
+	expect.Eq(t, replies, ``)
+	expect.Eq(t, node, 81)
+	expect.Eq(t, game.position().fen(), `rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1`)
+}
+
+// Game of any length.
+func TestUci040(t *testing.T) {
+	moves := strings.Repeat(` g1f3 g8f6 f3g1 f6g8`, 300)
+	expect.Eq(t, uci(`position startpos moves` + moves + ` e2e4`), ``)
+	expect.Eq(t, game.ply(), 1201)
+	expect.Eq(t, game.position().pieces[E4], Piece(Pawn))
+}
+
+// Chess960 castle sent as the king taking its own rook.
+func TestUci050(t *testing.T) {
+	replies := uci(`setoption name UCI_Chess960 value true`, `position fen 1r2k1r1/1p4p1/8/8/8/8/1P4P1/1R2K1R1 w GBgb - 0 1 moves e1b1 e8g8`)
+	expect.Eq(t, replies, ``)
+	expect.Eq(t, game.position().fen(), `1r3rk1/1p4p1/8/8/8/8/1P4P1/2KR2R1 w - - 2 1`)
+}
+
+// Pruning switches are on by default.
+func TestUci060(t *testing.T) {
+	defer func(saved Engine) { engine = saved }(engine)
+
+	engine.pruneLate, engine.pruneSee, engine.pruneGood = false, false, false
+	replies := uci(`setoption name LateMovePruning value false`, `setoption name ExchangePruning value false`, `uci`)
+	expect.Contain(t, replies, `option name LateMovePruning type check default true`)
+	expect.Contain(t, replies, `option name ExchangePruning type check default true`)
+	expect.Contain(t, replies, `option name HistoryPruning type check default true`)
+}
+
+// Advertised option defaults don't depend on the current settings.
+func TestUci070(t *testing.T) {
+	defer func(saved Engine) { engine = saved }(engine)
+
+	NewEngine()
+	expect.Eq(t, engine.evalCache, 16.0)
+
+	engine.evalCache, engine.pawnSize, engine.showWdl = 0, 8, true
+	engine.analysis, engine.contempt, engine.chess960, engine.overhead = true, 20, true, 100
+	replies := uci(`uci`)
+	expect.Contain(t, replies, `option name EvalHash type spin default 16 min 0 max 256`)
+	expect.Contain(t, replies, `option name PawnHash type spin default 2 min 1 max 64`)
+	expect.Contain(t, replies, `option name UCI_ShowWDL type check default false`)
+	expect.Contain(t, replies, `option name UCI_AnalyseMode type check default false`)
+	expect.Contain(t, replies, `option name Contempt type spin default 0 min -100 max 100`)
+	expect.Contain(t, replies, `option name UCI_Chess960 type check default false`)
+	expect.Contain(t, replies, `option name Move Overhead type spin default 10 min 0 max 5000`)
+}
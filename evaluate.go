@@ -27,6 +27,14 @@ type Safety struct {
 	attackers int 		// Number of pieces attacking king's fort.
 }
 
+// Square-level contribution to evaluation metric; used only when evaluation
+// tracing is enabled.
+type Attribution struct {
+	square int 		// Square the score is attributed to.
+	color  uint8 		// Side that gets the score.
+	score  Score 		// Score from that side's point of view.
+}
+
 // Helper structure used for evaluation tracking.
 type Total struct {
 	white Score 		// Score for white.
@@ -40,6 +48,7 @@ type MaterialEntry struct {
 	phase     int 		// Game phase based on available material.
 	turf      int 		// Home turf score for the game opening.
 	flags     uint8    	// Evaluation flags based on material balance.
+	bitbase   bool 		// Small endgame that might be found in bitbases.
 }
 
 type Evaluation struct {
@@ -48,9 +57,11 @@ type Evaluation struct {
 	attacks   [14]Bitmask 	 // Attack bitmasks for all the pieces on the board.
 	pins      [2]Bitmask     // Bitmask of pinned pieces for both sides.
 	pawns     *PawnEntry 	 // Pointer to the pawn cache entry.
+	pawnCache *PawnCache 	 // Pointer to the pawn cache.
 	material  *MaterialEntry // Pointer to the matrial base entry.
 	position  *Position 	 // Pointer to the position we're evaluating.
 	metrics   Metrics 	 // Evaluation metrics when tracking is on.
+	squares   map[string][]Attribution // Square-level metrics when tracking is on.
 }
 
 // Use single statically allocated variable to avoid garbage collection overhead.
@@ -59,6 +70,9 @@ var eval Evaluation
 // The following statement is true. The previous statement is false. Main position
 // evaluation method that returns single blended score.
 func (p *Position) Evaluate() int {
+	if engine.useNetwork && network != nil {
+		return network.evaluate(p)
+	}
 	return eval.init(p).run()
 }
 
@@ -67,6 +81,7 @@ func (p *Position) Evaluate() int {
 func (p *Position) EvaluateWithTrace() (int, Metrics) {
 	eval.init(p)
 	eval.metrics = make(Metrics)
+	eval.squares = make(map[string][]Attribution)
 
 	engine.trace = true
 	defer func() {
@@ -93,8 +108,8 @@ func (p *Position) EvaluateWithTrace() (int, Metrics) {
 }
 
 func (e *Evaluation) init(p *Position) *Evaluation {
-	eval = Evaluation{}
-	e.position = p
+	*e = Evaluation{}
+	e.position, e.pawnCache = p, engine.pawnCache
 
 	// Initialize the score with incremental PST value and right to move.
 	e.score = p.tally
@@ -121,22 +136,29 @@ func (e *Evaluation) init(p *Position) *Evaluation {
 	return e
 }
 
-func (e *Evaluation) run() int {
+func (e *Evaluation) run() (score int) {
 	e.material = &materialBase[e.position.balance]
 
 	e.score.add(e.material.score)
 	if e.material.flags & knownEndgame != 0 {
-		return e.evaluateEndgame()
+		score = e.evaluateEndgame()
+	} else {
+		e.analyzePawns()
+		e.analyzePieces()
+		e.analyzeThreats()
+		e.analyzeSpace()
+		e.analyzeSafety()
+		e.analyzePassers()
+		e.wrapUp()
+
+		score = e.score.blended(e.material.phase)
 	}
 
-	e.analyzePawns()
-	e.analyzePieces()
-	e.analyzeThreats()
-	e.analyzeSafety()
-	e.analyzePassers()
-	e.wrapUp()
+	if e.material.bitbase {
+		score = e.bitbaseEndgame(score)
+	}
 
-	return e.score.blended(e.material.phase)
+	return score
 }
 
 func (e *Evaluation) wrapUp() {
@@ -146,6 +168,14 @@ func (e *Evaluation) wrapUp() {
 		e.inspectEndgame()
 	}
 
+	// Scale down the endgame score if the pawns are locked and neither side
+	// can break through. Pawn endgames are left alone since the king could
+	// still get in and pick up the pawns, and so is extra material that could
+	// be sacrificed to break through.
+	if e.pawns.locked && e.material.phase > 0 && abs(e.materialBalance()) <= onePawn {
+		e.score.endgame = e.score.endgame * lockedScale / 100
+	}
+
 	// Flip the sign for black so that blended evaluation score always
 	// represents the white side.
 	if e.position.color == Black {
@@ -158,6 +188,22 @@ func (e *Evaluation) checkpoint(tag string, metric interface{}) {
 	e.metrics[tag] = metric
 }
 
+func (e *Evaluation) attribute(tag string, color uint8, square int, score Score) {
+	if e.squares != nil {
+		e.squares[tag] = append(e.squares[tag], Attribution{ square, color, score })
+	}
+}
+
+// Returns material balance from White's point of view based on midgame piece
+// values.
+func (e *Evaluation) materialBalance() (balance int) {
+	for piece := Piece(Pawn); piece < King; piece += 2 {
+		balance += (e.position.outposts[piece].count() - e.position.outposts[piece|Black].count()) * piece.value()
+	}
+
+	return balance
+}
+
 func (e *Evaluation) oppositeBishops() bool {
 	bishops := e.position.outposts[Bishop] | e.position.outposts[BlackBishop]
 
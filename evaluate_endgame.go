@@ -35,6 +35,24 @@ func (e *Evaluation) inspectEndgame() {
 	}
 }
 
+// Adjusts the score of small endgame found in bitbases: draws get zero score
+// whereas wins and losses become decisive while still preserving regular
+// evaluation to make progress. The score represents the side to move.
+func (e *Evaluation) bitbaseEndgame(score int) int {
+	if value, ok := e.position.probeBitbase(); ok {
+		switch value {
+		case bbDraw:
+			return DrawScore
+		case bbWin:
+			return WhiteWinning + max(score, 0)
+		case bbLoss:
+			return -WhiteWinning + min(score, 0)
+		}
+	}
+
+	return score
+}
+
 // Packs fractional markdown value as expected by inspectEndgame().
 func (e *Evaluation) fraction(mul, div int) int {
 	if mul == 1 {
@@ -51,16 +69,54 @@ func (e *Evaluation) strongerSide() uint8 {
 }
 
 // Known endgames where we calculate the exact score.
-func (e *Evaluation) winAgainstBareKing() int { 	// STUB.
-	return e.score.blended(e.material.phase)
+func (e *Evaluation) winAgainstBareKing() int {
+	color := e.strongerSide()
+	score := e.mateBareKing(color, abs(e.score.blended(e.material.phase)))
+
+	return let(color == White, score, -score)
 }
 
-func (e *Evaluation) knightAndBishopVsBareKing() int {	// STUB.
-	return e.score.blended(e.material.phase)
+func (e *Evaluation) knightAndBishopVsBareKing() int {
+	p, color := e.position, e.strongerSide()
+	if p.outposts[color^1].count() > 1 {
+		return e.score.blended(e.material.phase) // Weak side is not bare.
+	}
+
+	// Bare king can only be checkmated in the corner of bishop's color:
+	// flip the board horizontally for light-squared bishop. The knight
+	// should stay close to the bare king to take away its escape squares.
+	square := int(p.king[color^1])
+	corner := let((p.outposts[bishop(color)] & maskDark).empty(), square ^ 7, square)
+	knight := p.outposts[knight(color)].first()
+	score := e.mateBareKing(color, bonusCorner[corner] + bonusCloser[distance[knight][square]] / 2)
+
+	return let(color == White, score, -score)
 }
 
-func (e *Evaluation) twoBishopsVsBareKing() int { 	// STUB.
-	return e.score.blended(e.material.phase)
+func (e *Evaluation) twoBishopsVsBareKing() int {
+	p, color := e.position, e.strongerSide()
+	bishops := p.outposts[bishop(color)]
+	if (bishops & maskDark).empty() || (bishops & ^maskDark).empty() {
+		return DrawScore // Bishops of the same color can't checkmate.
+	}
+	if p.outposts[color^1].count() > 1 {
+		return e.score.blended(e.material.phase) // Weak side is not bare.
+	}
+
+	square := int(p.king[color^1])
+	score := e.mateBareKing(color, max(bonusCorner[square], bonusCorner[square ^ 7]))
+
+	return let(color == White, score, -score)
+}
+
+// Returns the score for the side that is mating the bare king: decisive
+// advantage plus extra bonus, plus bonuses for pushing the bare king to
+// the edge and bringing the kings closer to each other.
+func (e *Evaluation) mateBareKing(color uint8, bonus int) int {
+	p := e.position
+	strong, weak := int(p.king[color]), int(p.king[color^1])
+
+	return WhiteWinning + bonus + bonusBareKing[weak] + bonusCloser[distance[strong][weak]]
 }
 
 func (e *Evaluation) kingAndPawnVsBareKing() int {
@@ -201,15 +257,115 @@ func (e *Evaluation) kingAndPawnVsKingAndPawn() int {
 	return ExistingScore
 }
 
-func (e *Evaluation) bishopAndPawnVsBareKing() int {	// STUB.
+// Bishop and pawn(s) vs. bare king: rook pawns with the bishop that doesn't
+// control the promotion square can't win if the bare king gets there.
+func (e *Evaluation) bishopAndPawnVsBareKing() int {
+	p := e.position
+	color := uint8(let(p.outposts[Bishop].any(), White, Black))
+	pawns := p.outposts[pawn(color)]
+
+	column := let((pawns & ^maskFile[A1]).empty(), A1, let((pawns & ^maskFile[H1]).empty(), H1, -1))
+	if column < 0 {
+		return ExistingScore // Not all pawns are on the same rook file.
+	}
+
+	promo := square(let(color == White, A8H8, A1H1), column)
+	if (same(promo) & p.outposts[bishop(color)]).empty() && distance[p.king[color^1]][promo] <= 1 {
+		return DrawScore
+	}
+
 	return ExistingScore
 }
 
-func (e *Evaluation) rookAndPawnVsRook() int {		// STUB.
+// Rook and pawn vs. rook: recognize drawn Philidor and frontal defenses as
+// well as won Lucena position.
+func (e *Evaluation) rookAndPawnVsRook() int {
+	p := e.position
+	color := uint8(let(p.outposts[Pawn].any(), White, Black))
+	if p.outposts[rook(color)].count() != 1 || p.outposts[rook(color^1)].count() != 1 {
+		return ExistingScore // Both rooks on the same side.
+	}
+
+	// Normalize the squares as if the pawn was white and on A-D files.
+	flip := int(color) * 56
+	if col(p.outposts[pawn(color)].first()) > 3 {
+		flip ^= 7
+	}
+	wPawn := p.outposts[pawn(color)].first() ^ flip
+	wKing, wRook := int(p.king[color]) ^ flip, p.outposts[rook(color)].first() ^ flip
+	bKing, bRook := int(p.king[color^1]) ^ flip, p.outposts[rook(color^1)].first() ^ flip
+
+	tempo := let(p.color == color, 1, 0)
+	rank, promo := row(wPawn), square(A8H8, col(wPawn))
+
+	// Philidor position: defending king is in front of the pawn and the rook
+	// cuts off attacking king along the 6th rank.
+	if rank <= A5H5 && distance[bKing][promo] <= 1 && row(wKing) <= A5H5 &&
+	   (row(bRook) == A6H6 || (rank <= A3H3 && row(wRook) != A6H6)) {
+		return DrawScore
+	}
+
+	// After the pawn has reached the 6th rank the defending rook goes back
+	// to check the attacking king from behind.
+	if rank == A6H6 && distance[bKing][promo] <= 1 && row(wKing) + tempo <= A6H6 &&
+	   (row(bRook) == A1H1 || (tempo == 0 && abs(col(bRook) - col(wPawn)) >= 3)) {
+		return DrawScore
+	}
+	if rank >= A6H6 && bKing == promo && row(bRook) == A1H1 &&
+	   (tempo == 0 || distance[wKing][wPawn] >= 2) {
+		return DrawScore
+	}
+
+	// Rook pawn on the 7th with the rook in front of it, and the defending
+	// rook behind the pawn.
+	if wPawn == A7 && wRook == A8 && (bKing == G7 || bKing == H7) && col(bRook) == 0 &&
+	   (row(bRook) <= A3H3 || col(wKing) >= 3 || row(wKing) <= A5H5) {
+		return DrawScore
+	}
+
+	// Frontal defense: defending king blocks the pawn while attacking king
+	// is too far away to support the pawn or to chase defending rook, and
+	// the rooks don't attack each other.
+	if rank <= A5H5 && bKing == wPawn + 8 &&
+	   distance[wKing][wPawn] - tempo >= 2 && distance[wKing][bRook] - tempo >= 2 &&
+	   !p.rookMovesAt(p.outposts[rook(color)].first(), p.board).on(p.outposts[rook(color^1)].first()) {
+		return DrawScore
+	}
+
+	// Lucena position: attacking king shelters in front of the pawn on the
+	// 7th rank while defending king is cut off at least two files away.
+	if rank == A7H7 && col(wPawn) != 0 && distance[wKing][promo] <= 1 && row(wKing) >= rank &&
+	   abs(col(bKing) - col(wPawn)) >= 2 && col(wRook) != col(wPawn) {
+		return e.fraction(2, 1) // 2/1
+	}
+
+	// Defending king stays in front of the pawn that hasn't crossed the
+	// middle of the board yet.
+	if rank <= A4H4 && row(bKing) > rank {
+		if col(bKing) == col(wPawn) {
+			return e.fraction(1, 4) // 1/4
+		}
+		if abs(col(bKing) - col(wPawn)) == 1 && distance[wKing][bKing] > 2 {
+			return e.fraction(1, 2) // 1/2
+		}
+	}
+
 	return ExistingScore
 }
 
-func (e *Evaluation) queenVsRookAndPawns() int { 	// STUB.
+// Queen vs. rook and pawn(s): recognize the fortress where the rook on the
+// 3rd rank is protected by the pawn next to its king, and the attacking king
+// can't get through.
+func (e *Evaluation) queenVsRookAndPawns() int {
+	p := e.position
+	color := uint8(let(p.outposts[Queen].any(), White, Black))
+	king, rook := int(p.king[color^1]), p.outposts[rook(color^1)].first()
+
+	if rank(color^1, king) <= A2H2 && rank(color^1, int(p.king[color])) >= A4H4 && rank(color^1, rook) == A3H3 &&
+	   (p.outposts[pawn(color^1)] & kingMoves[king] & pawnAttacks[color][rook]).any() {
+		return DrawScore
+	}
+
 	return ExistingScore
 }
 
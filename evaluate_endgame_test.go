@@ -229,3 +229,176 @@ func TestEndgame380(t *testing.T) {
 	score := NewGame(`Kf1,h3`, `M,Kh1,h4`).start().Evaluate()
 	expect.Eq(t, score, 0)
 }
+
+// Mating the bare king.
+func TestEndgame400(t *testing.T) {
+	center := NewGame(`Kc3,Qh1`, `Ke5`).start().Evaluate()
+	edge := NewGame(`Kc3,Qh1`, `Ka5`).start().Evaluate()
+	expect.True(t, center > WhiteWinning)
+	expect.True(t, edge > center)
+}
+
+func TestEndgame410(t *testing.T) { // Dark-squared bishop mates in A1 or H8.
+	right := NewGame(`Kf6,Bc1,Ne5`, `Kg8`).start().Evaluate()
+	wrong := NewGame(`Kc6,Bc1,Nd5`, `Kb8`).start().Evaluate()
+	expect.True(t, wrong > WhiteWinning)
+	expect.True(t, right > wrong)
+}
+
+func TestEndgame420(t *testing.T) { // Light-squared bishop mates in A8 or H1.
+	right := NewGame(`Kc6,Bf1,Nd5`, `M,Kb8`).start().Evaluate()
+	wrong := NewGame(`Kc3,Bf1,Nd4`, `M,Kb1`).start().Evaluate()
+	expect.True(t, wrong < -WhiteWinning)
+	expect.True(t, right < wrong)
+}
+
+func TestEndgame430(t *testing.T) {
+	score := NewGame(`Kc3,Bc1,Be3`, `Ke5`).start().Evaluate()
+	expect.Eq(t, score, 0) // Same colored bishops.
+}
+
+// Returns number of moves it took to checkmate the bare king, or zero if
+// the checkmate was not delivered within the fifty move limit.
+func mateBareKing(p *Position, depth int) int {
+	for moves := 0; moves < 100; moves++ {
+		game.getReady()
+		NewRootGen(p, 1).generateRootMoves()
+		for iteration := 1; iteration <= depth; iteration++ {
+			p.search(-Checkmate, Checkmate, iteration)
+		}
+		if game.pv[0].size == 0 {
+			return let(p.isInCheck(p.color), (moves + 1) / 2, 0)
+		}
+		p = p.makeMove(game.pv[0].moves[0])
+	}
+
+	return 0
+}
+
+func TestEndgame440(t *testing.T) {
+	moves := mateBareKing(NewGame(`Kb2,Qh1`, `Ke5`).start(), 4)
+	expect.True(t, moves > 0 && moves <= 50)
+}
+
+func TestEndgame450(t *testing.T) {
+	moves := mateBareKing(NewGame(`Kb2,Rh1`, `Ke5`).start(), 4)
+	expect.True(t, moves > 0 && moves <= 50)
+}
+
+func TestEndgame460(t *testing.T) {
+	moves := mateBareKing(NewGame(`Kd1,Bc1,Bf1`, `Ke5`).start(), 5)
+	expect.True(t, moves > 0 && moves <= 50)
+}
+
+func TestEndgame470(t *testing.T) {
+	moves := mateBareKing(NewGame(`Kd1,Bc1,Nb1`, `Ke5`).start(), 5)
+	expect.True(t, moves > 0 && moves <= 50)
+}
+
+func TestEndgame480(t *testing.T) {
+	moves := mateBareKing(NewGame(`Ke1,Bf1,Ng1`, `Ka8`).start(), 5)
+	expect.True(t, moves > 0 && moves <= 50)
+}
+
+func TestEndgame490(t *testing.T) { // Black is mating.
+	moves := mateBareKing(NewGame(`Kd4`, `M,Kd8,Bf8,Ng8`).start(), 5)
+	expect.True(t, moves > 0 && moves <= 50)
+}
+
+// Rook and pawn vs. rook.
+func TestEndgame500(t *testing.T) { // Philidor position.
+	score := NewGame(`Kd5,Ra7,e5`, `Ke8,Rh6`).start().Evaluate()
+	expect.Eq(t, score, 0)
+}
+
+func TestEndgame510(t *testing.T) { // Philidor position, Black has the pawn.
+	score := NewGame(`Ke1,Rh3`, `M,Kd4,Ra2,e4`).start().Evaluate()
+	expect.Eq(t, score, 0)
+}
+
+func TestEndgame520(t *testing.T) { // Checking from behind once the pawn reaches 6th rank.
+	score := NewGame(`Kd5,Ra7,e6`, `Ke8,Rh1`).start().Evaluate()
+	expect.Eq(t, score, 0)
+}
+
+func TestEndgame530(t *testing.T) { // Frontal defense.
+	score := NewGame(`Kg2,Ra4,c4`, `Kc5,Rh8`).start().Evaluate()
+	expect.Eq(t, score, 0)
+
+	// Not when the rooks face each other.
+	score = NewGame(`Ka1,Rh2,d4`, `Kd5,Rh8`).start().Evaluate()
+	expect.True(t, score > 0)
+}
+
+func TestEndgame540(t *testing.T) { // Rook pawn with the rook in front of it.
+	score := NewGame(`Ka6,Ra8,a7`, `Kg7,Ra1`).start().Evaluate()
+	expect.Eq(t, score, 0)
+}
+
+func TestEndgame550(t *testing.T) { // Lucena position.
+	lucena := NewGame(`Kd8,Rf1,d7`, `Kg7,Rc2`).start().Evaluate()
+	score := NewGame(`Kd8,Rf1,d7`, `Ke7,Rc2`).start().Evaluate()
+	expect.True(t, lucena > score * 2)
+}
+
+func TestEndgame560(t *testing.T) { // Lucena position, Black has the pawn.
+	score := NewGame(`Kb2,Rf7`, `M,Ke1,Rc8,e2`).start().Evaluate()
+	expect.True(t, score > onePawn * 5)
+}
+
+func TestEndgame570(t *testing.T) {
+	score := NewGame(`Kd6,Rh1,e5`, `Kb8,Ra2`).start().Evaluate()
+	expect.True(t, score > onePawn)
+}
+
+// Bishop and pawn(s) vs. bare king.
+func TestEndgame600(t *testing.T) { // Wrong bishop.
+	score := NewGame(`Kc3,Bf1,h4`, `Kh8`).start().Evaluate()
+	expect.Eq(t, score, 0)
+}
+
+func TestEndgame610(t *testing.T) { // Wrong bishop, doubled pawns.
+	score := NewGame(`Kc3,Bf1,h4,h5`, `Kh8`).start().Evaluate()
+	expect.Eq(t, score, 0)
+}
+
+func TestEndgame620(t *testing.T) { // Wrong bishop, Black has the pawns.
+	score := NewGame(`Kg2`, `M,Kc7,Be7,h5,h3`).start().Evaluate()
+	expect.Eq(t, score, 0)
+}
+
+func TestEndgame630(t *testing.T) { // Right bishop.
+	score := NewGame(`Kc3,Bc1,h4,h5`, `Kh8`).start().Evaluate()
+	expect.True(t, score > onePawn)
+}
+
+func TestEndgame640(t *testing.T) { // Bare king is too far from the corner.
+	score := NewGame(`Kc3,Bf1,a4`, `Kd5`).start().Evaluate()
+	expect.True(t, score > onePawn)
+}
+
+func TestEndgame650(t *testing.T) { // Pawns on different files.
+	score := NewGame(`Kc3,Bf1,g4,h4`, `Kh8`).start().Evaluate()
+	expect.True(t, score > onePawn)
+}
+
+// Queen vs. rook and pawn(s).
+func TestEndgame700(t *testing.T) { // Fortress.
+	score := NewGame(`Kd5,Qa8`, `Kh7,Rf6,g7`).start().Evaluate()
+	expect.Eq(t, score, 0)
+}
+
+func TestEndgame710(t *testing.T) { // Fortress with two pawns, White has the rook.
+	score := NewGame(`Ka1,Rc3,b2,a2`, `M,Kf6,Qh1`).start().Evaluate()
+	expect.Eq(t, score, 0)
+}
+
+func TestEndgame720(t *testing.T) { // Attacking king got past the rook.
+	score := NewGame(`Ke6,Qa8`, `Kh7,Rf6,g7`).start().Evaluate()
+	expect.True(t, score > onePawn)
+}
+
+func TestEndgame730(t *testing.T) { // Rook is not protected by the pawn.
+	score := NewGame(`Kd5,Qa8`, `Kh7,Rf6,h6`).start().Evaluate()
+	expect.True(t, score > onePawn)
+}
@@ -4,28 +4,81 @@
 
 package donna
 
+import `unsafe`
+
 type PawnEntry struct {
-	id       uint64 	// Pawn hash key.
-	score    Score 		// Static score for the given pawn structure.
-	king     [2]uint8 	// King square for both sides.
-	cover    [2]Score 	// King cover penalties for both sides.
-	passers  [2]Bitmask 	// Passed pawn bitmasks for both sides.
+	id         uint64 	// Pawn hash key.
+	score      Score 	// Static score for the given pawn structure.
+	king       [2]uint8 	// King square for both sides.
+	valid      bool 	// False until the entry gets used; pawnless hash key is 0.
+	locked     bool 	// True if the pawns are blocked and neither side has pawn breaks.
+	cover      [2]Score 	// King cover penalties for both sides.
+	passers    [2]Bitmask 	// Passed pawn bitmasks for both sides.
+	candidates [2]Bitmask 	// Candidate passer bitmasks for both sides.
+}
+
+type PawnCache struct {
+	entries    []PawnEntry
+	probes     int 		// Number of pawn cache lookups.
+	hits       int 		// Number of lookups that found the pawn structure.
+	collisions int 		// Number of entries replaced by different pawn structure.
+}
+
+// Creates pawn cache of the given size in megabytes (2MB by default). The
+// number of entries gets rounded down to the power of two.
+func NewPawnCache(megaBytes float64) *PawnCache {
+	return &PawnCache{ entries: make([]PawnEntry, pawnCacheSize(megaBytes)) }
+}
+
+// Returns the number of pawn cache entries for the given size in megabytes.
+func pawnCacheSize(megaBytes float64) int {
+	if megaBytes <= 0.0 {
+		megaBytes = 2.0
+	}
+
+	cacheSize := max(1, int(1024 * 1024 * megaBytes) / int(unsafe.Sizeof(PawnEntry{})))
+	for cacheSize & (cacheSize - 1) != 0 {
+		cacheSize &= cacheSize - 1
+	}
+
+	return cacheSize
 }
 
-type PawnCache [8192*2]PawnEntry
+// Drops all cached pawn structures.
+func (cache *PawnCache) clear() {
+	for i := 0; i < len(cache.entries); i++ {
+		cache.entries[i] = PawnEntry{}
+	}
+}
+
+// Returns pawn cache hit rate in percents.
+func (cache *PawnCache) hitRate() int {
+	if cache.probes == 0 {
+		return 0
+	}
+
+	return cache.hits * 100 / cache.probes
+}
 
 func (e *Evaluation) analyzePawns() {
 	key := e.position.pawnId
+	cache := e.pawnCache
 
-	// Since pawn hash is fairly small we can use much faster 32-bit index.
-	index := uint32(key) % uint32(len(game.pawnCache))
-	e.pawns = &game.pawnCache[index]
+	// Full pawn hash key is compared, so the index could use its lower bits.
+	e.pawns = &cache.entries[key & uint64(len(cache.entries) - 1)]
+	cache.probes++
 
 	// Bypass pawns cache if evaluation tracing is enabled.
-	if e.pawns.id != key || engine.trace {
+	if !e.pawns.valid || e.pawns.id != key || engine.trace {
+		if e.pawns.valid && e.pawns.id != key {
+			cache.collisions++
+		}
+
 		white, black := e.pawnStructure(White), e.pawnStructure(Black)
-		e.pawns.score.clear().add(white).sub(black).apply(weightPawnStructure)
-		e.pawns.id = key
+		whiteCandidates, blackCandidates := e.pawnCandidates(White), e.pawnCandidates(Black)
+		e.pawns.score.clear().add(white).sub(black).add(whiteCandidates).sub(blackCandidates).apply(weightPawnStructure)
+		e.pawns.id, e.pawns.valid = key, true
+		e.pawns.locked = e.lockedPawns()
 
 		// Force full king shelter evaluation since any legit king square
 		// will be viewed as if the king has moved.
@@ -33,7 +86,10 @@ func (e *Evaluation) analyzePawns() {
 
 		if engine.trace {
 			e.checkpoint(`Pawns`, Total{white, black})
+			e.checkpoint(`Candidates`, Total{whiteCandidates, blackCandidates})
 		}
+	} else {
+		cache.hits++
 	}
 
 	e.score.add(e.pawns.score)
@@ -60,7 +116,7 @@ func (e *Evaluation) pawnStructure(our uint8) (score Score) {
 	their := our^1
 	ourPawns := e.position.outposts[pawn(our)]
 	theirPawns := e.position.outposts[pawn(their)]
-	e.pawns.passers[our] = 0
+	e.pawns.passers[our], e.pawns.candidates[our] = 0, 0
 
 	pawns := ourPawns
 	for pawns.any() {
@@ -124,19 +180,99 @@ func (e *Evaluation) pawnStructure(our uint8) (score Score) {
 			}
 		}
 
-		// Bonus if the pawn has good chance to become a passed pawn.
+		// Bonus if the pawn has good chance to become a passed pawn. Unless
+		// there is another friendly pawn in front the pawn also becomes
+		// a candidate passer that gets scored by pawnCandidates().
 		if exposed && !isolated && !passed && !backward {
 			his := maskPassed[their][square + up[our]] & maskIsolated[col] & ourPawns
 			her := maskPassed[our][square] & maskIsolated[col] & theirPawns
 			if his.count() >= her.count() {
 				score.add(bonusSemiPassedPawn[rank(our, square)])
+				if !doubled {
+					e.pawns.candidates[our].set(square)
+				}
 			}
 		}
 	}
 
+	// Bonus for healthy pawn majority on either wing, i.e. when at least two
+	// pawns on different files outnumber enemy pawns on the same wing. Passed
+	// pawns get their own bonus and don't count.
+	for _, wing := range maskWing {
+		files, majority := 0, ourPawns & ^e.pawns.passers[our]
+		for col := 0; col < 8; col++ {
+			if (wing & maskFile[col] & majority).any() {
+				files++
+			}
+		}
+		if files >= 2 && files > (wing & theirPawns).count() {
+			score.add(pawnMajority)
+		}
+	}
+
 	return score
 }
 
+// Calculates extra bonus for candidate passers found by pawnStructure(). The
+// bonus goes to the candidates that can safely step forward, i.e. the stop
+// square is defended by at least as many friendly pawns as it's attacked by
+// enemy pawns.
+func (e *Evaluation) pawnCandidates(our uint8) (score Score) {
+	their := our^1
+	ourPawns := e.position.outposts[pawn(our)]
+	theirPawns := e.position.outposts[pawn(their)]
+
+	pawns := e.pawns.candidates[our]
+	for pawns.any() {
+		square := pawns.pop()
+		stop := square + up[our]
+		if (pawnAttacks[our][stop] & theirPawns).count() <= (pawnAttacks[their][stop] & ourPawns).count() {
+			score.add(bonusCandidatePawn[rank(our, square)])
+		}
+	}
+
+	return score
+}
+
+// Returns true if neither side has passed pawns and none of the pawns can ever
+// get in contact with enemy pawns, i.e. the pawns are blocked with no pawn
+// breaks left.
+func (e *Evaluation) lockedPawns() bool {
+	p := e.position
+
+	if p.outposts[Pawn].empty() || p.outposts[BlackPawn].empty() {
+		return false
+	}
+	if (e.pawns.passers[White] | e.pawns.passers[Black]).any() {
+		return false
+	}
+
+	return !e.pawnBreaks(White) && !e.pawnBreaks(Black)
+}
+
+// Returns true if any of our pawns attacks enemy pawn or could attack it after
+// advancing. Since our pawn stepping onto the square attacked by enemy pawn
+// is also a break this covers enemy breaks against advancing pawns.
+func (e *Evaluation) pawnBreaks(our uint8) bool {
+	p, their := e.position, our^1
+	theirPawns := p.outposts[pawn(their)]
+	board := p.outposts[Pawn] | p.outposts[BlackPawn]
+
+	pawns := p.outposts[pawn(our)]
+	for pawns.any() {
+		for square := pawns.pop(); ; square += up[our] {
+			if (pawnAttacks[our][square] & theirPawns).any() || rank(our, square) == A7H7 {
+				return true
+			}
+			if board.on(square + up[our]) {
+				break
+			}
+		}
+	}
+
+	return false
+}
+
 func (e *Evaluation) pawnPassers(our uint8) (score Score) {
 	p, their := e.position, our^1
 
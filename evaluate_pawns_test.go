@@ -46,7 +46,7 @@ func TestEvaluatePawns220(t *testing.T) {
 	game := NewGame(`Ke1,a4,e4`, `Ke8,a5,d6`) // Can't pass.
 	score := game.start().Evaluate()
 
-	expect.Eq(t, score, -2)
+	expect.Eq(t, score, -9)
 }
 
 func TestEvaluatePawns230(t *testing.T) {
@@ -82,7 +82,7 @@ func TestEvaluatePawns310(t *testing.T) {
 	game := NewGame(`Ke1,a2,c2,e2`, `Ke8,a7,b7,c7`) // White pawns are isolated.
 	score := game.start().Evaluate()
 
-	expect.Eq(t, score, -28)
+	expect.Eq(t, score, -39)
 }
 
 // Rooks.
@@ -125,14 +125,14 @@ func TestEvaluatePawns510(t *testing.T) {
 	game := NewGame(`Kg1,f2,g2,h2,Qa3,Na4`, `Kg8,f5,g6,h7,Qa6,Na5`) // h2,g2,h2 vs F5,G6,h7
 	score := game.start().Evaluate()
 
-	expect.Eq(t, score, 35)
+	expect.Eq(t, score, 34)
 }
 
 func TestEvaluatePawns520(t *testing.T) {
 	game := NewGame(`Kg1,f2,g2,h2,Qa3,Na4`, `Kg8,a7,f7,g7,Qa6,Na5`) // h2,g2,h2 vs A7,f7,g7
 	score := game.start().Evaluate()
 
-	expect.Eq(t, score, 45)
+	expect.Eq(t, score, 52)
 }
 
 func TestEvaluatePawns530(t *testing.T) {
@@ -153,7 +153,7 @@ func TestEvaluatePawns550(t *testing.T) {
 	game := NewGame(`Kb1,b2,c2,h2,Qh3,Nh4`, `Kb8,a7,b7,c7,Qh6,Nh5`) // b2,c2,H2 vs a7,b7,c7
 	score := game.start().Evaluate()
 
-	expect.Eq(t, score, -25)
+	expect.Eq(t, score, -32)
 }
 
 func TestEvaluatePawns560(t *testing.T) {
@@ -175,26 +175,157 @@ func TestEvaluatePawns600(t *testing.T) {
 	game := NewGame(`Kd4,f2,g2,h2`, `Kg8,g7,h7,a3`) // Kd4-c3 stops A3 pawn.
 	score := game.start().Evaluate()
 
-	expect.Eq(t, score, -88)
+	expect.Eq(t, score, -77)
 }
 
 func TestEvaluatePawns610(t *testing.T) {
 	game := NewGame(`Kd4,f2,g2,h2`, `M99,Kg8,g7,h7,a3`) // a3-a2 makes the pawn unstoppable.
 	score := game.start().Evaluate()
 
-	expect.Eq(t, score, 1178)
+	expect.Eq(t, score, 1167)
 }
 
 func TestEvaluatePawns620(t *testing.T) {
 	game := NewGame(`Ka1,b4,g2`, `Kg8,g7,h7`) // b4-b5 is unstoppable.
 	score := game.start().Evaluate()
 
-	expect.Eq(t, score, 1053)
+	expect.Eq(t, score, 1042)
 }
 
 func TestEvaluatePawns630(t *testing.T) {
 	game := NewGame(`Ka1,b4,h2`, `M99,Kg8,g7,h7`) // Kg8-f8 stops B4 pawn.
 	score := game.start().Evaluate()
 
-	expect.Eq(t, score, 31)
+	expect.Eq(t, score, 42)
+}
+
+// Pawn cache size.
+func TestEvaluatePawns700(t *testing.T) {
+	expect.Eq(t, pawnCacheSize(0), pawnCacheSize(2))
+	expect.Eq(t, pawnCacheSize(2) & (pawnCacheSize(2) - 1), 0)
+	expect.Eq(t, pawnCacheSize(4), pawnCacheSize(2) * 2)
+	expect.Eq(t, pawnCacheSize(0.000001), 1)
+}
+
+// Pawn cache survives new game.
+func TestEvaluatePawns710(t *testing.T) {
+	NewGame(`Ke1,e4,d4`, `Ke8,e5`).start().Evaluate()
+	cache := engine.pawnCache
+	cache.probes, cache.hits, cache.collisions = 0, 0, 0
+
+	NewGame(`Kf1,e4,d4`, `Kf8,e5`).start().Evaluate()
+	expect.True(t, engine.pawnCache == cache)
+	expect.Eq(t, cache.probes, 1)
+	expect.Eq(t, cache.hits, 1)
+	expect.Eq(t, cache.hitRate(), 100)
+}
+
+// Pawnless positions don't match empty entries, and collisions get counted.
+func TestEvaluatePawns720(t *testing.T) {
+	defer func(size float64) { engine.pawnSize = size }(engine.pawnSize)
+
+	score := NewGame(`Kg1,Rb1`, `Kg8,Rb8`).start().Evaluate()
+	engine.pawnSize = 0.000001 // Single entry.
+	p := NewGame(`Kg1,Rb1`, `Kg8,Rb8`).start()
+	expect.Eq(t, len(engine.pawnCache.entries), 1)
+	expect.Eq(t, p.pawnId, uint64(0))
+	expect.Eq(t, p.Evaluate(), score)
+	expect.Eq(t, engine.pawnCache.hits, 0)
+
+	p = NewGame(`Kg1,Rb1,a2`, `Kg8,Rb8`).start()
+	p.Evaluate()
+	expect.Eq(t, engine.pawnCache.probes, 2)
+	expect.Eq(t, engine.pawnCache.collisions, 1)
+}
+
+// Single pawns and passed pawns don't make a majority.
+func TestEvaluatePawns800(t *testing.T) {
+	defer func(saved Score) { pawnMajority = saved }(pawnMajority)
+
+	for _, position := range [][]string{
+		{ `Ke1,d2`, `Ke8,e7` },         // Single pawns.
+		{ `Ke1,a2,h2`, `Ke8,h7` },      // Passer on empty wing.
+		{ `Ke1,a2,b2,g2`, `Ke8,g7` },   // Two passers.
+		{ `Ke1,a2,c2,g2`, `Ke8,d7,g7` }, // Passer and single pawn.
+	} {
+		p := NewGame(position[0], position[1]).start()
+		pawnMajority = Score{0, 10}
+		_, metrics := p.EvaluateWithTrace()
+		pawnMajority = Score{0, 0}
+		_, other := p.EvaluateWithTrace()
+		expect.Eq(t, metrics[`Pawns`], other[`Pawns`])
+	}
+}
+
+// Healthy majority on the queenside.
+func TestEvaluatePawns810(t *testing.T) {
+	defer func(saved Score) { pawnMajority = saved }(pawnMajority)
+
+	p := NewGame(`Ke1,a2,b2,c2,g2`, `Ke8,a7,b7,g7`).start()
+	_, metrics := p.EvaluateWithTrace()
+	majority := metrics[`Pawns`].(Total)
+
+	pawnMajority = Score{0, 0}
+	_, metrics = p.EvaluateWithTrace()
+	expect.Eq(t, majority.white.minus(metrics[`Pawns`].(Total).white), Score{0, 10})
+	expect.Eq(t, majority.black, metrics[`Pawns`].(Total).black)
+
+	// Doubled pawns don't make healthy majority.
+	p = NewGame(`Ke1,a2,b2,b3,g2`, `Ke8,a7,b7,g7`).start()
+	_, metrics = p.EvaluateWithTrace()
+	pawnMajority = Score{0, 10}
+	_, other := p.EvaluateWithTrace()
+	expect.Eq(t, metrics[`Pawns`], other[`Pawns`])
+}
+
+// Locked pawns scale down the endgame score.
+func TestEvaluatePawns820(t *testing.T) {
+	defer func(saved int) { lockedScale = saved }(lockedScale)
+
+	p := NewGame(`Kg1,Rd1,Nd5,a4,c4,h4`, `Kg8,Rd8,Nb8,a5,c5,h5`).start()
+	score := p.Evaluate()
+	expect.True(t, eval.pawns.locked)
+
+	lockedScale = 100
+	expect.True(t, p.Evaluate() > score)
+}
+
+// Locked pawns don't scale the score when one side is up a piece.
+func TestEvaluatePawns825(t *testing.T) {
+	defer func(saved int) { lockedScale = saved }(lockedScale)
+
+	p := NewGame(`Kg1,Rd1,Nc3,a4,c4,h4`, `Kg8,Rd8,a5,c5,h5`).start()
+	score := p.Evaluate()
+	expect.True(t, eval.pawns.locked)
+
+	lockedScale = 100
+	expect.Eq(t, p.Evaluate(), score)
+}
+
+// Pawn breaks and pawn endgames.
+func TestEvaluatePawns830(t *testing.T) {
+	NewGame(`Kg1,Rd1,a4,h4`, `Kg8,Rd8,b6,h5`).start().Evaluate()
+	expect.False(t, eval.pawns.locked)
+
+	NewGame(`Kg1,Rd1,a4,h4`, `Kg8,Rd8,b5,h5`).start().Evaluate()
+	expect.False(t, eval.pawns.locked)
+
+	NewGame(`Kf5,h3`, `Kd5,h4`).start().Evaluate() // Locked but no scaling.
+	expect.True(t, eval.pawns.locked)
+}
+
+// Candidate passer gets extra bonus if it can safely advance.
+func TestEvaluatePawns840(t *testing.T) {
+	defer func(saved [8]Score) { bonusCandidatePawn = saved }(bonusCandidatePawn)
+
+	_, metrics := NewGame(`Kg1,Ra1,b3,c4`, `Kg8,Rh8,d6`).start().EvaluateWithTrace()
+	expect.Eq(t, metrics[`Candidates`], Total{})
+
+	p := NewGame(`Kg1,Ra1,b4,c4`, `Kg8,Rh8,d6`).start()
+	score, metrics := p.EvaluateWithTrace()
+	expect.Eq(t, metrics[`Candidates`], Total{ bonusCandidatePawn[3], Score{} })
+
+	bonusCandidatePawn = [8]Score{}
+	other, _ := p.EvaluateWithTrace()
+	expect.True(t, score > other)
 }
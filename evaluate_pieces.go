@@ -115,7 +115,11 @@ func (e *Evaluation) knights(our uint8, maskSafe Bitmask, unsafeKing bool) (scor
 		// Bonus for knight's mobility -- unless the knight is pinned.
 		if e.pins[our].off(square) {
 			attacks = p.attacks(square)
-			mobility.add(mobilityKnight[(attacks & maskSafe).count()])
+			bonus := mobilityKnight[(attacks & maskSafe).count()]
+			mobility.add(bonus)
+			if engine.trace {
+				e.attribute(`Mobility`, our, square, bonus)
+			}
 		}
 
 		// Penalty if knight is attacked by enemy's pawn.
@@ -152,8 +156,11 @@ func (e *Evaluation) bishops(our uint8, maskSafe Bitmask, unsafeKing bool) (scor
 		if e.pins[our].on(square) {
 			attacks &= maskLine[p.king[our]][square]
 		}
-		mobility.add(mobilityBishop[(attacks & maskSafe).count()])
-
+		bonus := mobilityBishop[(attacks & maskSafe).count()]
+		mobility.add(bonus)
+		if engine.trace {
+			e.attribute(`Mobility`, our, square, bonus)
+		}
 
 		// Penalty for light/dark-colored pawns restricting a bishop.
 		if count := (same(square) & p.outposts[pawn(our)]).count(); count > 0 {
@@ -225,6 +232,9 @@ func (e *Evaluation) rooks(our uint8, maskSafe Bitmask, unsafeKing bool) (score,
 		}
 		safeSquares := (attacks & maskSafe).count()
 		mobility.add(mobilityRook[safeSquares])
+		if engine.trace {
+			e.attribute(`Mobility`, our, square, mobilityRook[safeSquares])
+		}
 
 		// Penalty if rook is attacked by enemy's pawn.
 		if maskPawn[their][square] & theirPawns != 0 {
@@ -294,7 +304,11 @@ func (e *Evaluation) queens(our uint8, maskSafe Bitmask, unsafeKing bool) (score
 		if e.pins[our].on(square) {
 			attacks &= maskLine[p.king[our]][square]
 		}
-		mobility.add(mobilityQueen[min(15, (attacks & maskSafe).count())])
+		bonus := mobilityQueen[min(15, (attacks & maskSafe).count())]
+		mobility.add(bonus)
+		if engine.trace {
+			e.attribute(`Mobility`, our, square, bonus)
+		}
 
 		// Penalty if queen is attacked by enemy's pawn.
 		if (maskPawn[their][square] & p.outposts[pawn(their)]).any() {
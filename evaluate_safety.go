@@ -65,7 +65,7 @@ func (e *Evaluation) kingSafety(our uint8) (score Score) {
 	checks := weak & e.attacks[queen(their)] & protected & ^p.outposts[their]
 	if checks.any() {
 		checkers++
-		safetyIndex += queenCheck * checks.count()
+		safetyIndex += queenContact * checks.count()
 	}
 
 	// Out of all squares available for enemy pieces select the ones
@@ -76,27 +76,42 @@ func (e *Evaluation) kingSafety(our uint8) (score Score) {
 	// us a check?
 	if checks := knightMoves[square] & safe & e.attacks[knight(their)]; checks.any() {
 		checkers++
-		safetyIndex += checks.count()
+		safetyIndex += safeCheck[Knight/2] * checks.count()
 	}
 
 	// Are there any safe squares from where enemy Bishop could give us a check?
 	safeBishopMoves := p.bishopMoves(square) & safe
 	if checks := safeBishopMoves & e.attacks[bishop(their)]; checks.any() {
 		checkers++
-		safetyIndex += checks.count()
+		safetyIndex += safeCheck[Bishop/2] * checks.count()
 	}
 
 	// Are there any safe squares from where enemy Rook could give us a check?
 	safeRookMoves := p.rookMoves(square) & safe
 	if checks := safeRookMoves & e.attacks[rook(their)]; checks.any() {
 		checkers++
-		safetyIndex += checks.count()
+		safetyIndex += safeCheck[Rook/2] * checks.count()
 	}
 
 	// Are there any safe squares from where enemy Queen could give us a check?
 	if checks := (safeBishopMoves | safeRookMoves) & e.attacks[queen(their)]; checks.any() {
 		checkers++
-		safetyIndex += queenCheck / 2 * checks.count()
+		safetyIndex += safeCheck[Queen/2] * checks.count()
+	}
+
+	// Semi-open and open files next to the king are dangerous when the
+	// enemy has rooks or queens to use them.
+	if (p.outposts[rook(their)] | p.outposts[queen(their)]).any() {
+		col := col(square)
+		for c := max(B1, col) - 1; c <= min(G1, col) + 1; c++ {
+			if (p.outposts[pawn(our)] & maskFile[c]).empty() {
+				if (p.outposts[pawn(their)] & maskFile[c]).empty() {
+					safetyIndex += kingFileDanger[1]
+				} else {
+					safetyIndex += kingFileDanger[0]
+				}
+			}
+		}
 	}
 
 	threatIndex := min(16, e.safety[our].attackers * e.safety[our].threats / 2) +
@@ -158,15 +173,15 @@ func (e *Evaluation) kingCoverBonus(our uint8, square int) (bonus int) {
 		}
 		bonus -= penaltyCover[closest]
 
-		// Enemy pawns facing the king.
+		// Enemy pawns facing the king, scaled by the file.
 		if pawns := (storm & maskFile[c]); pawns.any() {
-			farthest := rank(our, pawns.farthest(our^1))
+			farthest, scale := rank(our, pawns.farthest(our^1)), stormFile[min(c, 7 - c)]
 			if closest == 0 { // No opposing friendly pawn.
-				bonus -= penaltyStorm[farthest]
+				bonus -= penaltyStorm[farthest] * scale / 100
 			} else if farthest == closest + 1 {
-				bonus -= penaltyStormBlocked[farthest]
+				bonus -= penaltyStormBlocked[farthest] * scale / 100
 			} else {
-				bonus -= penaltyStormUnblocked[farthest]
+				bonus -= penaltyStormUnblocked[farthest] * scale / 100
 			}
 		}
 	}
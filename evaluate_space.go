@@ -0,0 +1,122 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+// Evaluates space behind the pawn chain, minor piece outposts, and piece
+// coordination. Relies on attack bitmasks built by analyzePieces().
+func (e *Evaluation) analyzeSpace() {
+	var space, outposts, coordination Total
+
+	if engine.trace {
+		defer func() {
+			e.checkpoint(`Space`, space)
+			e.checkpoint(`Outposts`, outposts)
+			e.checkpoint(`Coordination`, coordination)
+		}()
+	}
+
+	space.white = e.space(White)
+	space.black = e.space(Black)
+	e.score.add(space.white).sub(space.black)
+
+	outposts.white = e.outposts(White)
+	outposts.black = e.outposts(Black)
+	e.score.add(outposts.white).sub(outposts.black)
+
+	coordination.white = e.coordination(White)
+	coordination.black = e.coordination(Black)
+	e.score.add(coordination.white).sub(coordination.black)
+}
+
+// Counts safe squares on the center files of our half of the board, with the
+// squares behind our pawns counted twice. The bonus grows with the number of
+// pieces since extra space matters less when there is nothing to maneuver.
+func (e *Evaluation) space(our uint8) (score Score) {
+	p, their := e.position, our^1
+
+	pawns := p.outposts[pawn(our)]
+	safe := homeTurf[our] & ^pawns & ^e.attacks[pawn(their)]
+
+	behind := pawns
+	if our == White {
+		behind |= behind >> 8   // A4..H4 -> A3..H3
+		behind |= behind >> 16  // A4..H4 | A3..H3 -> A2..H2 | A1..H1
+	} else {
+		behind |= behind << 8   // A5..H5 -> A6..H6
+		behind |= behind << 16  // A5..H5 | A6..H6 -> A7..H7 | A8..H8
+	}
+
+	if count := safe.count() + (safe & behind).count(); count > 0 {
+		pieces := (p.outposts[our] ^ pawns ^ p.outposts[king(our)]).count()
+		score = spaceSquare.times(count * pieces * pieces / 16)
+	}
+
+	return score
+}
+
+// Bonus for knights and bishops on the enemy's half of the board that can't
+// be attacked by enemy pawns. The bonus is doubled if the piece is supported
+// by our pawn.
+func (e *Evaluation) outposts(our uint8) (score Score) {
+	p, their := e.position, our^1
+
+	pieces := p.outposts[knight(our)] | p.outposts[bishop(our)]
+	for pieces.any() {
+		square := pieces.pop()
+		if row := rank(our, square); row < 3 || row > 5 {
+			continue
+		}
+
+		// Enemy pawns on adjacent files in front of the square might
+		// eventually attack it.
+		if (maskPassed[our][square] & ^maskFile[col(square)] & p.outposts[pawn(their)]).any() {
+			continue
+		}
+
+		outpost := bishopOutpost
+		if p.pieces[square].isKnight() {
+			outpost = knightOutpost
+		}
+		if e.attacks[pawn(our)].on(square) {
+			outpost = outpost.times(2)
+		}
+		score.add(outpost)
+		if engine.trace {
+			e.attribute(`Outposts`, our, square, outpost)
+		}
+	}
+
+	return score
+}
+
+// Bonus for rooks defending each other along a rank or file, and for queen
+// and bishop lined up on a diagonal that leads to the enemy king.
+func (e *Evaluation) coordination(our uint8) (score Score) {
+	p, their := e.position, our^1
+
+	if rooks := p.outposts[rook(our)]; rooks.count() > 1 {
+		square := rooks.pop()
+		if (p.rookMoves(square) & rooks).any() {
+			score.add(rookConnected)
+		}
+	}
+
+	if queens, bishops := p.outposts[queen(our)], p.outposts[bishop(our)]; queens.any() && bishops.any() {
+		fort := e.attacks[king(their)] | p.outposts[king(their)]
+		for queens.any() {
+			square := queens.pop()
+			lined := p.bishopMoves(square) & bishops
+			for lined.any() {
+				other := lined.pop()
+				targets := p.bishopMovesAt(square, p.board ^ bit[other]) | p.bishopMovesAt(other, p.board ^ bit[square])
+				if (targets & maskLine[square][other] & fort).any() {
+					score.add(queenBattery)
+				}
+			}
+		}
+	}
+
+	return score
+}
@@ -18,7 +18,7 @@ func TestEvaluate010(t *testing.T) {
 	p := NewGame(`Ra1,Nb1,Bc1,Qd1,Ke1,Bf1,Ng1,Rh1,a2,b2,c2,d2,e4,f2,g2,h2`,
 		`M1,Ra8,Nb8,Bc8,Qd8,Ke8,Bf8,Ng8,Rh8,a7,b7,c7,d7,e7,f7,g7,h7`).start()
 	score := p.Evaluate()
-	expect.Eq(t, score, -90) // +90 for white.
+	expect.Eq(t, score, -102) // +102 for white.
 }
 
 // After 1. e2-e4 e7-e5
@@ -85,3 +85,98 @@ func TestEvaluate073(t *testing.T) {
 	eval.init(p)
 	expect.False(t, eval.oppositeBishops())
 }
+
+// Returns king safety and cover scores for the given side.
+func kingSafetyMetrics(p *Position, color uint8) (safety, cover Score) {
+	_, metrics := p.EvaluateWithTrace()
+	if color == White {
+		return metrics[`-Safety`].(Total).white, metrics[`-Cover`].(Total).white
+	}
+	return metrics[`-Safety`].(Total).black, metrics[`-Cover`].(Total).black
+}
+
+// Safe checks.
+func TestEvaluate100(t *testing.T) {
+	defer func(saved [6]int) { safeCheck = saved }(safeCheck)
+
+	p := NewGame(`Kg1,Ra1,f2,g2,h2`, `Kg8,Qd5,Nd4,Re8,f7,g7,h7`).start()
+	safety, _ := kingSafetyMetrics(p, White)
+
+	safeCheck = [6]int{}
+	unsafe, _ := kingSafetyMetrics(p, White)
+	expect.True(t, safety.midgame < unsafe.midgame)
+}
+
+// Open and semi-open files next to the king.
+func TestEvaluate110(t *testing.T) {
+	defer func(saved [2]int) { kingFileDanger = saved }(kingFileDanger)
+
+	p := NewGame(`Kg1,Ra1,f2,g2`, `Kg8,Qd5,Nd4,Rh8,f7,g7,h7`).start()
+	open, _ := kingSafetyMetrics(p, White)
+	p = NewGame(`Kg1,Ra1,f2,g2,h3`, `Kg8,Qd5,Nd4,Rh8,f7,g7,h7`).start()
+	closed, _ := kingSafetyMetrics(p, White)
+	expect.True(t, open.midgame < closed.midgame)
+
+	kingFileDanger = [2]int{}
+	p = NewGame(`Kg1,Ra1,f2,g2`, `Kg8,Qd5,Nd4,Rh8,f7,g7,h7`).start()
+	safety, _ := kingSafetyMetrics(p, White)
+	expect.True(t, open.midgame < safety.midgame)
+}
+
+// Pawn storm gets scaled by the file.
+func TestEvaluate120(t *testing.T) {
+	defer func(saved [4]int) { stormFile = saved }(stormFile)
+
+	p := NewGame(`Kg1,Ra1,f2,g2,h2`, `Kg8,Qd8,Re8,f7,g4,h7`).start()
+	_, storm := kingSafetyMetrics(p, White)
+
+	stormFile[1] = 50 // B and G files.
+	p = NewGame(`Kg1,Ra1,f2,g2,h2`, `Kg8,Qd8,Re8,f7,g4,h7`).start()
+	_, half := kingSafetyMetrics(p, White)
+	expect.True(t, storm.midgame < half.midgame)
+}
+
+func totalMetric(p *Position, tag string) Total {
+	_, metrics := p.EvaluateWithTrace()
+	return metrics[tag].(Total)
+}
+
+// Space behind the pawn chain.
+func TestEvaluate130(t *testing.T) {
+	p := NewGame(`Ra1,Nb1,Bc1,Qd1,Ke1,Bf1,Ng1,Rh1,a2,b2,c2,d4,e4,f2,g2,h2`,
+		`Ra8,Nb8,Bc8,Qd8,Ke8,Bf8,Ng8,Rh8,a7,b7,c7,d7,e7,f7,g7,h7`).start()
+	space := totalMetric(p, `Space`)
+	expect.True(t, space.white.midgame > space.black.midgame)
+	expect.Eq(t, space.white.endgame, 0)
+
+	p = NewGame(`Ke1,Ra1,a2,b2,c2,d4,e4,f2,g2,h2`, `Ke8,Ra8,a7,b7,c7,d7,e7,f7,g7,h7`).start()
+	expect.True(t, totalMetric(p, `Space`).white.midgame < space.white.midgame)
+}
+
+// Knight and bishop outposts.
+func TestEvaluate140(t *testing.T) {
+	p := NewGame(`Kg1,Nd5,Bf4,e4,a2,b2,g2,h2`, `Kg8,Nb8,Bc8,a7,b7,f7,h7`).start()
+	outposts := totalMetric(p, `Outposts`)
+	expect.Eq(t, outposts.white, knightOutpost.times(2).plus(bishopOutpost))
+	expect.Eq(t, outposts.black, Score{0, 0})
+
+	p = NewGame(`Kg1,Nd5,Bf4,e4,a2,b2,g2,h2`, `Kg8,Nb8,Bc8,a7,b7,c7,f7,g6,h7`).start()
+	expect.Eq(t, totalMetric(p, `Outposts`).white, Score{0, 0})
+}
+
+// Connected rooks.
+func TestEvaluate150(t *testing.T) {
+	p := NewGame(`Kg1,Ra1,Rf1,a2,b2,g2,h2`, `Kg8,Ra8,Nb8,Rf8,a7,b7,g7,h7`).start()
+	coordination := totalMetric(p, `Coordination`)
+	expect.Eq(t, coordination.white, rookConnected)
+	expect.Eq(t, coordination.black, Score{0, 0})
+}
+
+// Queen and bishop battery.
+func TestEvaluate160(t *testing.T) {
+	p := NewGame(`Kg1,Qd3,Bc2,Ra1,f2,g2,h2`, `Kh7,Qd8,Ra8,a7,b7,g7,h6`).start()
+	expect.Eq(t, totalMetric(p, `Coordination`).white, queenBattery)
+
+	p = NewGame(`Kg1,Qd3,Bc2,Ra1,f2,g2,h2`, `Kb8,Qd8,Ra8,a7,b7,g7,h6`).start()
+	expect.Eq(t, totalMetric(p, `Coordination`).white, Score{0, 0})
+}
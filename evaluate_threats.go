@@ -43,8 +43,11 @@ func (e *Evaluation) threats(our uint8) (score Score) {
 
 	// Bonus for each enemy piece attacked by our pawn.
 	for targets.any() {
-		piece := p.pieces[targets.pop()]
-		score.add(bonusPawnThreat[piece.id()])
+		square := targets.pop()
+		score.add(bonusPawnThreat[p.pieces[square].id()])
+		if engine.trace {
+			e.attribute(`Threats`, our, square, bonusPawnThreat[p.pieces[square].id()])
+		}
 	}
 
 	// Find enemy pieces that might be our likely targets: major pieces
@@ -56,15 +59,21 @@ func (e *Evaluation) threats(our uint8) (score Score) {
 		// Bonus for enemy pieces attacked by knights and bishops.
 		targets = likely & (e.attacks[knight(our)] | e.attacks[bishop(our)])
 		for targets.any() {
-			piece := p.pieces[targets.pop()]
-			score.add(bonusMinorThreat[piece.id()])
+			square := targets.pop()
+			score.add(bonusMinorThreat[p.pieces[square].id()])
+			if engine.trace {
+				e.attribute(`Threats`, our, square, bonusMinorThreat[p.pieces[square].id()])
+			}
 		}
 
 		// Bonus for enemy pieces attacked by rooks.
 		targets = (undefended | p.outposts[queen(their)]) & e.attacks[rook(our)]
 		for targets.any() {
-			piece := p.pieces[targets.pop()]
-			score.add(bonusRookThreat[piece.id()])
+			square := targets.pop()
+			score.add(bonusRookThreat[p.pieces[square].id()])
+			if engine.trace {
+				e.attribute(`Threats`, our, square, bonusRookThreat[p.pieces[square].id()])
+			}
 		}
 
 		// Bonus for enemy pieces attacked by the king.
@@ -74,6 +83,11 @@ func (e *Evaluation) threats(our uint8) (score Score) {
 				if count > 1 {
 					score.add(kingAttack)
 				}
+				if engine.trace {
+					for i := 0; i < min(2, count); i++ {
+						e.attribute(`Threats`, our, targets.pop(), kingAttack)
+					}
+				}
 			}
 		}
 
@@ -81,6 +95,11 @@ func (e *Evaluation) threats(our uint8) (score Score) {
 		if targets = undefended & ^e.attacks[their]; targets.any() {
 			if count := targets.count(); count > 0 {
 				score.add(hangingAttack.times(count))
+				if engine.trace {
+					for targets.any() {
+						e.attribute(`Threats`, our, targets.pop(), hangingAttack)
+					}
+				}
 			}
 		}
 	}
@@ -0,0 +1,162 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`encoding/json`
+	`sort`
+)
+
+// Evaluation metrics in the order they get reported. The terms prefixed with
+// "+" are sums of the "-" terms that follow.
+var traceTags = []string{
+	`PST`, `Imbalance`, `Tempo`, `Center`, `Threats`, `Pawns`, `Candidates`, `Passers`, `Mobility`,
+	`Space`, `Outposts`, `Coordination`, `+Pieces`, `-Knights`, `-Bishops`, `-Rooks`, `-Queens`, `+King`, `-Cover`, `-Safety`,
+}
+
+// Structured evaluation trace that lists every evaluation term.
+type Trace struct {
+	Position string      `json:"position"` // Position FEN.
+	Phase    int         `json:"phase"`    // Game phase used to blend the scores.
+	Score    int         `json:"score"`    // Evaluation score for the side to move.
+	Terms    []TraceTerm `json:"terms"`
+}
+
+// Evaluation term: White and Black scores, if the term is tracked for each
+// side, and the total score from White's point of view.
+type TraceTerm struct {
+	Name    string        `json:"name"`
+	Group   string        `json:"group,omitempty"` // Parent term, ex. "Pieces" for "Knights".
+	White   *TraceScore   `json:"white,omitempty"`
+	Black   *TraceScore   `json:"black,omitempty"`
+	Total   TraceScore    `json:"total"`
+	Squares []TraceSquare `json:"squares,omitempty"`
+}
+
+// Square-level contribution to evaluation term. The score is from the point
+// of view of the side that gets it, ex. threat bonus for attacking the piece.
+type TraceSquare struct {
+	Square string     `json:"square"`
+	Piece  string     `json:"piece"` // Piece on the square.
+	Color  string     `json:"color"` // Side that gets the score.
+	Score  TraceScore `json:"score"`
+}
+
+// Midgame, endgame, and blended values of the score.
+type TraceScore struct {
+	Midgame int `json:"midgame"`
+	Endgame int `json:"endgame"`
+	Blended int `json:"blended"`
+}
+
+// Evaluates the position and returns the trace of all evaluation terms along
+// with square-level attributions for PST, mobility, and threats.
+func (p *Position) Trace() *Trace {
+	fen := p.fen()
+	score, metrics := p.EvaluateWithTrace()
+
+	trace := &Trace{ Position: fen, Score: score, Terms: traceTerms(metrics) }
+	trace.Phase, _ = metrics[`Phase`].(int)
+
+	// Square-level attributions are only available for the terms evaluated
+	// piece by piece. PST values come straight from the table.
+	squares := eval.squares
+	for square, piece := range p.pieces {
+		if !piece.nil() {
+			value := pst[piece][square]
+			if piece.isBlack() {
+				value = Score{}.minus(value)
+			}
+			squares[`PST`] = append(squares[`PST`], Attribution{ square, piece.color(), value })
+		}
+	}
+
+	for i, term := range trace.Terms {
+		for _, attribution := range squares[term.Name] {
+			trace.Terms[i].Squares = append(trace.Terms[i].Squares, TraceSquare{
+				Square: squareName(attribution.square),
+				Piece:  plainPiece(p.pieces[attribution.square]),
+				Color:  C(attribution.color),
+				Score:  traceScore(attribution.score, trace.Phase),
+			})
+		}
+	}
+
+	return trace
+}
+
+// Returns JSON representation of the trace.
+func (t *Trace) JSON() ([]byte, error) {
+	return json.MarshalIndent(t, ``, `  `)
+}
+
+// Converts evaluation metrics to the list of terms. The terms missing in the
+// metrics are skipped while unknown ones get appended in alphabetical order.
+// The final score always goes last.
+func traceTerms(metrics Metrics) (terms []TraceTerm) {
+	phase, _ := metrics[`Phase`].(int)
+
+	known := map[string]bool{ `Phase`: true, `Final`: true }
+	for _, tag := range traceTags {
+		known[tag] = true
+	}
+	extra := []string{}
+	for tag := range metrics {
+		if !known[tag] {
+			extra = append(extra, tag)
+		}
+	}
+	sort.Strings(extra)
+	tags := append(append(append([]string{}, traceTags...), extra...), `Final`)
+
+	group := ``
+	for _, tag := range tags {
+		metric, ok := metrics[tag]
+		if !ok {
+			continue
+		}
+
+		term := TraceTerm{ Name: tag }
+		if tag[0] == '+' {
+			term.Name, group = tag[1:], tag[1:]
+		} else if tag[0] == '-' {
+			term.Name, term.Group = tag[1:], group
+		}
+
+		switch metric.(type) {
+		case Total:
+			white, black := metric.(Total).white, metric.(Total).black
+			ours, theirs := traceScore(white, phase), traceScore(black, phase)
+			term.White, term.Black = &ours, &theirs
+			term.Total = traceScore(white.minus(black), phase)
+		case Score:
+			term.Total = traceScore(metric.(Score), phase)
+		default:
+			continue
+		}
+		terms = append(terms, term)
+	}
+
+	return
+}
+
+// Returns score values along with the blended one.
+func traceScore(score Score, phase int) TraceScore {
+	return TraceScore{ score.midgame, score.endgame, score.blended(phase) }
+}
+
+// Returns square name, ex. "e4".
+func squareName(square int) string {
+	row, col := coordinate(square)
+	return string([]byte{ byte(col) + 'a', byte(row) + '1' })
+}
+
+// Returns plain piece letter regardless of fancy setting.
+func plainPiece(piece Piece) string {
+	fancy := engine.fancy
+	engine.fancy = false; defer func() { engine.fancy = fancy }()
+
+	return piece.String()
+}
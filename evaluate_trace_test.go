@@ -0,0 +1,105 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `encoding/json`; `testing`)
+
+// Returns the term with the given name.
+func traceTerm(trace *Trace, name string) (term TraceTerm) {
+	for _, term = range trace.Terms {
+		if term.Name == name {
+			return
+		}
+	}
+	return TraceTerm{}
+}
+
+// Terms are listed in order with white/black and total scores.
+func TestTrace000(t *testing.T) {
+	p := NewGame(`r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3`).start()
+	trace := p.Trace()
+	expect.Eq(t, trace.Position, `r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 1`)
+	expect.Eq(t, trace.Score, p.Evaluate())
+	expect.Eq(t, trace.Terms[0].Name, `PST`)
+	expect.Eq(t, trace.Terms[len(trace.Terms) - 1].Name, `Final`)
+	expect.Eq(t, trace.Terms[len(trace.Terms) - 1].Total.Blended, trace.Score)
+
+	knights := traceTerm(trace, `Knights`)
+	expect.Eq(t, knights.Group, `Pieces`)
+	expect.Eq(t, knights.Total.Midgame, knights.White.Midgame - knights.Black.Midgame)
+	expect.True(t, traceTerm(trace, `PST`).White == nil)
+}
+
+// Square-level attributions add up to the term scores.
+func TestTrace010(t *testing.T) {
+	trace := NewGame(`r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3`).start().Trace()
+
+	pst, total := traceTerm(trace, `PST`), 0
+	expect.Eq(t, len(pst.Squares), 32)
+	for _, square := range pst.Squares {
+		total += let(square.Color == `white`, square.Score.Midgame, -square.Score.Midgame)
+	}
+	expect.Eq(t, total, pst.Total.Midgame)
+
+	mobility, white, black := traceTerm(trace, `Mobility`), 0, 0
+	for _, square := range mobility.Squares {
+		if square.Color == `white` {
+			white += square.Score.Endgame
+		} else {
+			black += square.Score.Endgame
+		}
+	}
+	expect.Eq(t, white, mobility.White.Endgame)
+	expect.Eq(t, black, mobility.Black.Endgame)
+	expect.Eq(t, mobility.Squares[0].Square, `b1`)
+	expect.Eq(t, mobility.Squares[0].Piece, `N`)
+}
+
+// Threat attributions point to the pieces being attacked.
+func TestTrace020(t *testing.T) {
+	trace := NewGame(`Ke1,Nc3,d4`, `Ke8,Bc5,Ne5`).start().Trace()
+	threats := traceTerm(trace, `Threats`)
+	expect.Eq(t, len(threats.Squares), 4)
+	expect.Eq(t, threats.Squares[0].Square, `c5`)
+	expect.Eq(t, threats.Squares[0].Piece, `b`)
+	expect.Eq(t, threats.Squares[0].Color, `white`)
+	expect.Eq(t, threats.Squares[1].Square, `e5`)
+	expect.Eq(t, threats.White.Midgame, threats.Squares[0].Score.Midgame + threats.Squares[1].Score.Midgame)
+
+	// Black bishop attacks hanging pawn.
+	expect.Eq(t, threats.Squares[2].Square, `d4`)
+	expect.Eq(t, threats.Squares[2].Color, `black`)
+	expect.Eq(t, threats.Squares[3].Score.Midgame, hangingAttack.midgame)
+	expect.Eq(t, threats.Black.Endgame, threats.Squares[2].Score.Endgame + threats.Squares[3].Score.Endgame)
+}
+
+// Known endgames lack most of the terms.
+func TestTrace030(t *testing.T) {
+	_, metrics := NewGame(`Ke1,Qd1`, `Ke8`).start().EvaluateWithTrace()
+	terms := traceTerms(metrics)
+	expect.Eq(t, len(terms), 4)
+	expect.Eq(t, terms[0].Name, `PST`)
+	expect.Eq(t, terms[1].Name, `Imbalance`)
+	expect.Eq(t, terms[2].Name, `Tempo`)
+	expect.Eq(t, terms[3].Name, `Final`)
+
+	metrics[`Space`] = Total{ Score{ 10, 0 }, Score{} }
+	terms = traceTerms(metrics)
+	expect.Eq(t, terms[3].Name, `Space`)
+	expect.Eq(t, terms[3].Total, TraceScore{ 10, 0, 10 * metrics[`Phase`].(int) / 256 })
+}
+
+// JSON export.
+func TestTrace040(t *testing.T) {
+	data, err := NewGame().start().Trace().JSON()
+	expect.True(t, err == nil)
+	expect.Contain(t, string(data), `"position": "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"`)
+	expect.Contain(t, string(data), `"group": "Pieces"`)
+
+	var trace Trace
+	expect.True(t, json.Unmarshal(data, &trace) == nil)
+	expect.Eq(t, trace.Score, NewGame().start().Evaluate())
+	expect.Eq(t, traceTerm(&trace, `Mobility`).Squares[0].Square, `b1`)
+}
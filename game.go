@@ -7,7 +7,6 @@ package donna
 import (
 	`fmt`
 	`strings`
-	`time`
 )
 
 type RootPv struct {
@@ -21,17 +20,25 @@ type Killers [MaxPly][2]Move
 type Game struct {
 	nodes       int 	// Number of regular nodes searched.
 	qnodes      int 	// Number of quiescence nodes searched.
+	tbhits      int 	// Number of successful tablebase probes.
+	evals       int 	// Number of evaluation cache probes.
+	evalhits    int 	// Number of evaluation cache hits.
 	token       uint8 	// Cache's expiration token.
 	deepening   bool 	// True when searching first root move.
 	improving   bool 	// True when root search score is not falling.
 	volatility  float32 	// Root search stability count.
+	stability   int 	// Number of iterations the best move stays the same.
+	drop        int 	// Root score drop since previous iteration.
+	nullPly     int 	// Null move verification: no null moves below this ply...
+	nullColor   uint8 	// ...for the side being verified.
 	initial     string   	// Initial position (FEN or algebraic).
+	past        []Position 	// Earlier game positions that no longer fit into the tree.
 	history     History  	// Good moves history.
 	killers     Killers  	// Killer moves.
 	rootpv      RootPv 	// Principal variation for root moves.
 	pv          Pv  	// Principal variations for each ply.
 	cache       Cache 	// Transposition table.
-	pawnCache   PawnCache 	// Cache of pawn structures.
+	evalCache   EvalCache 	// Cache of evaluation scores.
 }
 
 // Use single statically allocated variable.
@@ -44,7 +51,12 @@ var game Game
 // The second option is a bit less pricise (ex. no en-passant square) but it is
 // much more useful when writing tests from memory.
 func NewGame(args ...string) *Game {
-	game = Game{ cache: NewCache(engine.cacheSize), pawnCache: PawnCache{} }
+	game = Game{ cache: NewCache(engine.cacheSize), evalCache: NewEvalCache(engine.evalCache) }
+
+	// Pawn cache is allocated once per engine and survives across games.
+	if engine.pawnCache == nil || len(engine.pawnCache.entries) != pawnCacheSize(engine.pawnSize) {
+		engine.pawnCache = NewPawnCache(engine.pawnSize)
+	}
 
 	switch len(args) {
 	case 0: // Initial position.
@@ -59,8 +71,7 @@ func NewGame(args ...string) *Game {
 }
 
 func (game *Game) start() *Position {
-	engine.clock.halt = false
-	tree, node, rootNode = [1024]Position{}, 0, 0
+	game.reset()
 
 	// Was the game started with FEN or algebraic notation?
 	sides := strings.Split(game.initial, ` : `)
@@ -70,10 +81,80 @@ func (game *Game) start() *Position {
 	return NewPositionFromFEN(game, game.initial)
 }
 
+// Same as start() but also makes sure the initial position is legal.
+func (game *Game) setup() (*Position, error) {
+	game.reset()
+
+	sides := strings.Split(game.initial, ` : `)
+	if len(sides) == 2 {
+		return ParseDCF(sides[White], sides[Black])
+	}
+	return ParseFEN(game.initial)
+}
+
+// Clears the search tree and the moves made so far to get ready for setting up
+// the initial position.
+func (game *Game) reset() {
+	engine.clock.halt = false
+	tree, node, rootNode = [1024]Position{}, 0, 0
+	game.past = nil
+}
+
 func (game *Game) position() *Position {
 	return &tree[node]
 }
 
+// Returns game position at the given tree index. Negative indexes refer to
+// earlier positions that have been moved out of the tree.
+func (game *Game) at(index int) *Position {
+	if index >= 0 {
+		return &tree[index]
+	}
+	return &game.past[len(game.past) + index]
+}
+
+// Returns the number of half-moves made since the game has started.
+func (game *Game) ply() int {
+	return len(game.past) + node
+}
+
+// Makes the move in the game. Unlike the search that always has enough room
+// left by getReady() the game could run out of tree nodes, so this is where we
+// check for it rather than slowing down Position.makeMove().
+func (game *Game) makeMove(move Move) *Position {
+	if node + 1 == len(tree) {
+		game.compact()
+	}
+
+	return game.position().makeMove(move)
+}
+
+// Makes room in the tree by moving the earliest positions out to the game
+// history, leaving the last quarter of the tree occupied. Must not be called
+// during the search since it invalidates position pointers.
+func (game *Game) compact() *Position {
+	if shift := node - len(tree) / 4; shift > 0 {
+		game.past = append(game.past, tree[:shift]...)
+		copy(tree[:], tree[shift:node + 1])
+		node, rootNode = node - shift, max(0, rootNode - shift)
+	}
+
+	return &tree[node]
+}
+
+// Brings earlier positions back from the game history when taking back the
+// moves all the way to the start of the tree.
+func (game *Game) restore() *Position {
+	if shift := min(len(game.past), len(tree) / 4); shift > 0 {
+		copy(tree[shift:], tree[:node + 1])
+		copy(tree[:], game.past[len(game.past) - shift:])
+		game.past = game.past[:len(game.past) - shift]
+		node, rootNode = node + shift, rootNode + shift
+	}
+
+	return &tree[node]
+}
+
 // Resets principal variation as well as killer moves and move history. Cache
 // entries get expired by incrementing cache token. Root node gets set to the
 // current tree node to match the position.
@@ -85,8 +166,14 @@ func (game *Game) getReady() *Game {
 	game.deepening = false
 	game.improving = true
 	game.volatility = 0.0
+	game.stability, game.drop = 0, 0
+	game.nullPly = 0
 	game.token++ // <-- Wraps around: ...254, 255, 0, 1...
 
+	// Leave enough room in the tree for the search.
+	if node > len(tree) / 2 {
+		game.compact()
+	}
 	rootNode = node
 	return game
 }
@@ -102,14 +189,16 @@ func updateRootPv() {
 // "The question of whether machines can think is about as relevant as the
 // question of whether submarines can swim." -- Edsger W. Dijkstra
 func (game *Game) Think() Move {
-	start := time.Now()
+	start := engine.now()
 	position := game.position()
-	game.nodes, game.qnodes = 0, 0
+	game.nodes, game.qnodes, game.tbhits = 0, 0, 0
+	game.evals, game.evalhits = 0, 0
+	engine.pawnCache.probes, engine.pawnCache.hits, engine.pawnCache.collisions = 0, 0, 0
 
 	if len(engine.bookFile) != 0 {
 		if book, err := NewBook(engine.bookFile); err == nil {
 			if move := book.pickMove(position); move != 0 {
-				game.printBestMove(move, since(start))
+				game.printBestMove(move, engine.since(start))
 				return move
 			}
 		} else if !engine.uci {
@@ -121,7 +210,7 @@ func (game *Game) Think() Move {
 	score, move, status, alpha, beta := 0, Move(0), InProgress, -Checkmate, Checkmate
 
 	if !engine.uci {
-		fmt.Println(`Depth   Time     Nodes    QNodes   Nodes/s    Score   Best`)
+		fmt.Println(`Depth   Time     Nodes    QNodes   Nodes/s    Score    W    D    L   Best`)
 	}
 
 	if !engine.fixedDepth() {
@@ -130,10 +219,12 @@ func (game *Game) Think() Move {
 
 	for depth := 1; game.keepThinking(depth, status, move); depth++ {
 		// Save previous best score in case search gets interrupted.
-		bestScore := score
+		bestScore, previous := score, score
 
-		// Assume volatility decreases with each new iteration.
+		// Assume volatility decreases with each new iteration, and root
+		// score is not falling until the search fails low.
 		game.volatility /= 2.0
+		game.improving = true
 
 		// At low depths do the search with full alpha/beta spread.
 		// Aspiration window searches kick in at depth 5 and up.
@@ -179,12 +270,19 @@ func (game *Game) Think() Move {
 			score = bestScore
 		}
 
+		// Track best move stability and root score drop so that time
+		// management could spend less on easy moves and more on fail lows.
+		if depth > 1 {
+			game.drop = max(0, previous - score)
+			game.stability = let(game.rootpv.moves[0] == move, game.stability + 1, 0)
+		}
+
 		move = game.rootpv.moves[0]
 		status = position.status(move, score)
-		game.printPrincipal(depth, score, status, since(start))
+		game.printPrincipal(depth, score, status, engine.since(start))
 	}
 
-	game.printBestMove(move, since(start))
+	game.printBestMove(move, engine.since(start))
 
 	return move
 }
@@ -210,7 +308,7 @@ func (game *Game) keepThinking(depth, status int, move Move) bool {
 
 	// Stop if the time left is not enough to gets through the next iteration.
 	if engine.varyingTime() {
-		elapsed := engine.elapsed(time.Now())
+		elapsed := engine.elapsed(engine.now())
 		remaining := engine.factor(depth, game.volatility).remaining()
 
 		//\\ engine.debug("# Depth %02d Volatility %.2f Elapsed %s Remaining %s\n", depth, game.volatility, ms(elapsed), ms(remaining))
@@ -8,7 +8,7 @@ func (gen *MoveGen) generateRootMoves() *MoveGen {
 	gen.generateAllMoves()
 
 	if !gen.onlyMove() {
-		gen.validOnly().rank(Move(0))
+		gen.validOnly().rank(Move(0)).tablebaseMoves()
 	}
 
 	return gen
@@ -68,12 +68,14 @@ func (gen *MoveGen) kingMoves(color uint8) *MoveGen {
 		square := int(gen.p.king[color])
 		gen.moveKing(square, gen.p.targets(square))
 
+		// Castles are added directly since in Chess960 the king might
+		// move just one square or stay where it is.
 		kingside, queenside := gen.p.canCastle(color)
 		if kingside {
-			gen.moveKing(square, bit[G1 + 56 * color])
+			gen.add(NewCastle(gen.p, square, G1 + 56 * int(color)))
 		}
 		if queenside {
-			gen.moveKing(square, bit[C1 + 56 * color])
+			gen.add(NewCastle(gen.p, square, C1 + 56 * int(color)))
 		}
 	}
 
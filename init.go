@@ -56,9 +56,31 @@ var (
 	// Late move reductions indexed by depth and move number.
 	lateMoveReductions [64][64]int
 
+	// Number of moves to search before pruning remaining quiet moves,
+	// indexed by depth.
+	lateMovePruning [8]int
+
 	// Precomputed database of material imbalance scores, evaluation flags,
 	// and endgame handlers. I wish they all could be California girls.
 	materialBase [2*2*3*3*3*3*3*3*9*9]MaterialEntry
+
+	// Syzygy tablebase indexing. Pawn squares A2-H7 map to 0..47 so that
+	// the leading pawn has the highest value, squares below A1-H8 diagonal
+	// map to 0..27, squares of A1-D1-D4 triangle map to 0..9, and legal
+	// pairs of kings map to 0..461.
+	tbMapPawns [64]int
+	tbMapB1H1H7 [64]int
+	tbMapA1D1D4 [64]int
+	tbMapKK [10][64]int
+
+	// Binomial coefficients: there are tbBinomial[k][n] ways to choose k
+	// squares out of n.
+	tbBinomial [tbPieces][64]uint64
+
+	// Leading pawn group index and size for each file, indexed by number
+	// of leading pawns.
+	tbLeadPawnIdx [tbPieces][64]uint64
+	tbLeadPawnsSize [tbPieces][4]uint64
 )
 
 func init() {
@@ -66,6 +88,7 @@ func init() {
 	initArrays()
 	initPST()
 	initMaterial()
+	initTablebase()
 }
 
 func initMasks() {
@@ -187,6 +210,11 @@ func initArrays() {
 			lateMoveReductions[i][j] = int(math.Floor(value))
 		}
 	}
+
+	// Late move pruning.
+	for depth := 0; depth < len(lateMovePruning); depth++ {
+		lateMovePruning[depth] = 3 + depth * depth * 2
+	}
 }
 
 func initPST() {
@@ -243,7 +271,8 @@ func initMaterial() {
 
 		// Set up evaluation flags and endgame handlers.
 		materialBase[index].flags,
-		materialBase[index].endgame = endgames(wP, wN, wB, wR, wQ, bP, bN, bB, bR, bQ)
+		materialBase[index].endgame,
+		materialBase[index].bitbase = endgames(wP, wN, wB, wR, wQ, bP, bN, bB, bR, bQ)
 
 		// Compute material imbalance scores.
 		if wQ != bQ || wR != bR || wB != bB || wN != bN || wP != bP {
@@ -280,7 +309,7 @@ func imbalance(w2, wP, wN, wB, wR, wQ, b2, bP, bN, bB, bR, bQ int) int {
 	       polynom(wQ,    0, (-177*w2 +  25*wP + 129*wN + 142*wB + -137*wR + 98*b2 + 105*bP + -39*bN + 141*bB + 274*bR),  -137)
 }
 
-func endgames(wP, wN, wB, wR, wQ, bP, bN, bB, bR, bQ int) (flags uint8, endgame Function) {
+func endgames(wP, wN, wB, wR, wQ, bP, bN, bB, bR, bQ int) (flags uint8, endgame Function, bitbase bool) {
 	wMinor, wMajor := wN + wB, wR + wQ
 	bMinor, bMajor := bN + bB, bR + bQ
 	allMinor, allMajor := wMinor + bMinor, wMajor + bMajor
@@ -345,8 +374,8 @@ func endgames(wP, wN, wB, wR, wQ, bP, bN, bB, bR, bQ int) (flags uint8, endgame
 		flags |= lesserKnownEndgame
 		endgame = (*Evaluation).kingAndPawnVsKingAndPawn
 
-	// Lesser known endgame: bishop and pawn vs. bare king.
-	} else if bareKing && allMajor == 0 && wN + bN == 0 && (wB * wP == 1 || bB * bP == 1) {
+	// Lesser known endgame: bishop and pawn(s) vs. bare king.
+	} else if bareKing && allMajor == 0 && wN + bN == 0 && ((wB == 1 && wP > 0) || (bB == 1 && bP > 0)) {
 		flags |= lesserKnownEndgame
 		endgame = (*Evaluation).bishopAndPawnVsBareKing
 
@@ -377,6 +406,10 @@ func endgames(wP, wN, wB, wR, wQ, bP, bN, bB, bR, bQ int) (flags uint8, endgame
 		}
 	}
 
+	// Endgames with up to four pieces (kings included) might be looked up
+	// in bitbases if they have been generated or loaded.
+	bitbase = (flags & materialDraw == 0 && wP + bP + allMinor + allMajor <= bbPieces - 2)
+
 	return
 }
 
@@ -577,3 +610,100 @@ func setupMasks(square, target, row, col, r, c int) {
 		}
 	}
 }
+
+func initTablebase() {
+	// Squares below A1-H8 diagonal.
+	code := 0
+	for sq := A1; sq <= H8; sq++ {
+		if offDiagonal(sq) < 0 {
+			tbMapB1H1H7[sq] = code
+			code++
+		}
+	}
+
+	// Squares of A1-D1-D4 triangle, with the ones on the diagonal last.
+	code, onDiagonal := 0, []int{}
+	for sq := A1; sq <= D4; sq++ {
+		if col(sq) <= 3 {
+			if offDiagonal(sq) < 0 {
+				tbMapA1D1D4[sq] = code
+				code++
+			} else if offDiagonal(sq) == 0 {
+				onDiagonal = append(onDiagonal, sq)
+			}
+		}
+	}
+	for _, sq := range onDiagonal {
+		tbMapA1D1D4[sq] = code
+		code++
+	}
+
+	// Legal positions of two kings where the first one is in A1-D1-D4
+	// triangle. If the first king is on the diagonal then the second one
+	// can't be above it. Both kings on the diagonal come last.
+	code, bothOnDiagonal := 0, [][2]int{}
+	for index := 0; index < 10; index++ {
+		for one := A1; one <= D4; one++ {
+			if tbMapA1D1D4[one] != index || (index == 0 && one != B1) {
+				continue
+			}
+			for two := A1; two <= H8; two++ {
+				if two == one || kingMoves[one].on(two) {
+					continue
+				}
+				if offDiagonal(one) == 0 {
+					if offDiagonal(two) > 0 {
+						continue
+					} else if offDiagonal(two) == 0 {
+						bothOnDiagonal = append(bothOnDiagonal, [2]int{index, two})
+						continue
+					}
+				}
+				tbMapKK[index][two] = code
+				code++
+			}
+		}
+	}
+	for _, pair := range bothOnDiagonal {
+		tbMapKK[pair[0]][pair[1]] = code
+		code++
+	}
+
+	// Pascal's triangle.
+	tbBinomial[0][0] = 1
+	for n := 1; n < 64; n++ {
+		for k := 0; k < tbPieces && k <= n; k++ {
+			if k > 0 {
+				tbBinomial[k][n] += tbBinomial[k-1][n-1]
+			}
+			if k < n {
+				tbBinomial[k][n] += tbBinomial[k][n-1]
+			}
+		}
+	}
+
+	// Pawn squares and leading pawn groups. The tables are split by the file
+	// of the leading pawn so the index restarts for every file.
+	available := 47
+	for leading := 1; leading < tbPieces - 1; leading++ {
+		for file := 0; file < 4; file++ {
+			index := uint64(0)
+			for row := A2H2; row <= A7H7; row++ {
+				sq := square(row, file)
+				if leading == 1 {
+					tbMapPawns[sq], tbMapPawns[sq ^ 7] = available, available - 1
+					available -= 2
+				}
+				tbLeadPawnIdx[leading][sq] = index
+				index += tbBinomial[leading - 1][tbMapPawns[sq]]
+			}
+			tbLeadPawnsSize[leading][file] = index
+		}
+	}
+}
+
+// Returns positive number for squares above A1-H8 diagonal, negative number
+// for the squares below it, and zero for the diagonal itself.
+func offDiagonal(square int) int {
+	return row(square) - col(square)
+}
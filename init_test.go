@@ -205,6 +205,15 @@ func TestMaterial120(t *testing.T) {
 	expect.Eq(t, p.balance, balance)
 }
 
+func TestMaterial125(t *testing.T) {
+	balance := 2 * materialBalance[BlackPawn] + materialBalance[BlackBishop]
+	expect.Eq(t, materialBase[balance].flags, uint8(lesserKnownEndgame))
+	expect.Eq(t, materialBase[balance].endgame, (*Evaluation).bishopAndPawnVsBareKing)
+
+	p := NewGame(`Ke1`, `Ka8,Bb7,h5,h4`).start()
+	expect.Eq(t, p.balance, balance)
+}
+
 // Lesser known endgame: rook and pawn vs. rook.
 func TestMaterial130(t *testing.T) {
 	balance := materialBalance[Rook] + materialBalance[Pawn] + materialBalance[BlackRook]
@@ -7,6 +7,7 @@ package donna
 import (
 	`bytes`
 	`regexp`
+	`strings`
 )
 
 const (
@@ -70,9 +71,16 @@ func NewMoveFromNotation(p *Position, e2e4 string) Move {
 	from := square(int(e2e4[1] - '1'), int(e2e4[0] - 'a'))
 	to := square(int(e2e4[3] - '1'), int(e2e4[2] - 'a'))
 
-	// Check if this is a castle.
-	if p.pieces[from].isKing() && abs(from - to) == 2 {
-		return NewCastle(p, from, to)
+	// Check if this is a castle. Chess960 castle is encoded as the king
+	// capturing its own rook, and the king moving two squares is the castle
+	// in standard chess.
+	if piece := p.pieces[from]; piece.isKing() {
+		if p.pieces[to] == rook(piece.color()) {
+			return NewCastle(p, from, let(to > from, G1, C1) + 56 * int(piece.color()))
+		}
+		if !engine.chess960 && abs(from - to) == 2 {
+			return NewCastle(p, from, to)
+		}
 	}
 
 	// Special handling for pawn pushes because they might cause en-passant
@@ -97,6 +105,22 @@ func NewMoveFromNotation(p *Position, e2e4 string) Move {
 	return NewMove(p, from, to)
 }
 
+// Decodes a move in UCI notation, ex. e2e4 or e7e8q, and makes sure it is
+// legal in the given position. Malformed or illegal moves are returned as
+// Move(0).
+func NewMoveFromUci(p *Position, e2e4 string) Move {
+	if !reUciMove.MatchString(e2e4) {
+		return Move(0)
+	}
+
+	move := NewMoveFromNotation(p, e2e4)
+	if !NewGen(p, MaxPly).generateAllMoves().validOnly().amongValid(move) {
+		return Move(0)
+	}
+
+	return move
+}
+
 // Decodes a string in long algebraic notation and returns a move. All invalid
 // moves are discarded and returned as Move(0).
 func NewMoveFromString(p *Position, e2e4 string) (move Move, validMoves []Move) {
@@ -105,7 +129,7 @@ func NewMoveFromString(p *Position, e2e4 string) (move Move, validMoves []Move)
 
 	// Before returning the move make sure it is valid in current position.
 	defer func() {
-		gen := NewMoveGen(p).generateAllMoves().validOnly()
+		gen := NewGen(p, MaxPly).generateAllMoves().validOnly()
 		validMoves = gen.allMoves()
 		if move != Move(0) && !gen.amongValid(move) {
 			move = Move(0)
@@ -157,6 +181,93 @@ func NewMoveFromString(p *Position, e2e4 string) (move Move, validMoves []Move)
 	return
 }
 
+// Decodes a string in standard algebraic notation, for example Nbd7, exd5,
+// O-O, or e8=Q+. Invalid and ambiguous moves are returned as Move(0).
+func NewMoveFromSan(p *Position, san string) (move Move) {
+	san = strings.Replace(strings.TrimRight(san, `+#!?`), `0`, `O`, -1)
+
+	kind, to, from, promo, castle := Pawn, -1, ``, 0, false
+	switch san {
+	case `O-O`:
+		kind, to, castle = King, G1 + int(p.color) * A8, true
+	case `O-O-O`:
+		kind, to, castle = King, C1 + int(p.color) * A8, true
+	default:
+		if index := strings.IndexAny(san, `=QRBN`); index > 0 && san[0] >= 'a' && san[0] <= 'h' {
+			promo = pieceKind(san[len(san) - 1])
+			san = san[:index]
+		} else if len(san) > 0 && strings.IndexByte(`KQRBN`, san[0]) >= 0 {
+			kind = pieceKind(san[0])
+			san = san[1:]
+		}
+		san = strings.Replace(strings.Replace(san, `x`, ``, -1), `-`, ``, -1)
+		if len(san) < 2 || len(san) > 4 || promo < 0 {
+			return Move(0)
+		}
+		row, col := int(san[len(san) - 1]) - '1', int(san[len(san) - 2]) - 'a'
+		if row < 0 || row > 7 || col < 0 || col > 7 {
+			return Move(0)
+		}
+		to, from = square(row, col), san[:len(san) - 2]
+	}
+
+	gen := NewGen(p, MaxPly).generateAllMoves().validOnly()
+	for _, valid := range gen.allMoves() {
+		if valid.piece().kind() != kind || valid.to() != to || valid.promo().kind() != promo || valid.isCastle() != castle {
+			continue
+		}
+
+		// Optional source file and/or rank to disambiguate the move.
+		if !sanSource(from, valid.from()) {
+			continue
+		}
+		if move != Move(0) {
+			return Move(0) // Ambiguous move.
+		}
+		move = valid
+	}
+
+	return move
+}
+
+// Returns true if optional source file and/or rank used to disambiguate the
+// move in standard algebraic notation (ex. "b" in Nbd2, "1" in R1e2, or "g1"
+// in Ng1f3) match the given source square.
+func sanSource(from string, square int) bool {
+	row, col := coordinate(square)
+
+	switch len(from) {
+	case 0:
+		return true
+	case 1:
+		if from[0] >= 'a' && from[0] <= 'h' {
+			return int(from[0]) - 'a' == col
+		}
+		return int(from[0]) - '1' == row
+	case 2:
+		return int(from[0]) - 'a' == col && int(from[1]) - '1' == row
+	}
+
+	return false
+}
+
+// Returns piece kind for the given letter, or -1 if the letter is invalid.
+func pieceKind(letter byte) int {
+	switch letter {
+	case 'K':
+		return King
+	case 'Q':
+		return Queen
+	case 'R':
+		return Rook
+	case 'B':
+		return Bishop
+	case 'N':
+		return Knight
+	}
+	return -1
+}
+
 func (m Move) nil() bool {
 	return m == Move(0)
 }
@@ -271,6 +382,9 @@ func (m Move) notation() string {
 	var buffer bytes.Buffer
 
 	from, to, _, _ := m.split()
+	if engine.chess960 && m.isCastle() { // King takes rook.
+		to = castleRookFrom[m.color()][let(col(to) == 6, 0, 1)]
+	}
 	buffer.WriteByte(byte(col(from)) + 'a')
 	buffer.WriteByte(byte(row(from)) + '1')
 	buffer.WriteByte(byte(col(to)) + 'a')
@@ -301,7 +415,7 @@ func (m Move) String() (str string) {
 
 	from, to, piece, capture := m.split()
 	if m.isCastle() {
-		if to > from {
+		if col(to) == 6 {
 			return `0-0`
 		}
 		return `0-0-0`
@@ -233,3 +233,58 @@ func TestMove340(t *testing.T) {
 	expect.Eq(t, bK & isCapture, Move(0))
 	expect.Ne(t, bP & isCapture, Move(0)) // Ne() for Pawn.
 }
+
+// Standard algebraic notation.
+func TestMove400(t *testing.T) {
+	p := NewGame(`Ke1,Ra1,Rh1,Nb3,Nf3,e5,g7`, `Kc8,Rd8,d5,f7`).start()
+	expect.Eq(t, NewMoveFromSan(p, `O-O`), NewCastle(p, E1, G1))
+	expect.Eq(t, NewMoveFromSan(p, `0-0-0`), NewCastle(p, E1, C1))
+	expect.Eq(t, NewMoveFromSan(p, `Nbd2`), NewMove(p, B3, D2))
+	expect.Eq(t, NewMoveFromSan(p, `Nfd2+`), NewMove(p, F3, D2))
+	expect.Eq(t, NewMoveFromSan(p, `Nd2`), Move(0)) // Ambiguous.
+	expect.Eq(t, NewMoveFromSan(p, `N3d2`), Move(0)) // Ambiguous.
+	expect.Eq(t, NewMoveFromSan(p, `Nb3d2`), NewMove(p, B3, D2))
+	expect.Eq(t, NewMoveFromSan(p, `N3bd2`), Move(0))
+	expect.Eq(t, NewMoveFromSan(p, `Nbbd2`), Move(0))
+	expect.Eq(t, NewMoveFromSan(p, `R1b1`), NewMove(p, A1, B1))
+	expect.Eq(t, NewMoveFromSan(p, `Rhg1`), NewMove(p, H1, G1))
+	expect.Eq(t, NewMoveFromSan(p, `Rab1`), NewMove(p, A1, B1))
+	expect.Eq(t, NewMoveFromSan(p, `e6`), NewPawnMove(p, E5, E6))
+	expect.Eq(t, NewMoveFromSan(p, `g8=Q`), NewMove(p, G7, G8).promote(Queen))
+	expect.Eq(t, NewMoveFromSan(p, `g8N`), NewMove(p, G7, G8).promote(Knight))
+	expect.Eq(t, NewMoveFromSan(p, `g8`), Move(0))
+	expect.Eq(t, NewMoveFromSan(p, `Ke3`), Move(0))
+	expect.Eq(t, NewMoveFromSan(p, `Qd1`), Move(0))
+	expect.Eq(t, NewMoveFromSan(p, `Zz`), Move(0))
+}
+
+func TestMove410(t *testing.T) {
+	p := NewGame(`Ke1,e5`, `M,Kc8,d7`).start()
+	move := NewMoveFromSan(p, `d5`)
+	expect.Eq(t, move, NewEnpassant(p, D7, D5))
+
+	p = p.makeMove(move)
+	expect.Eq(t, NewMoveFromSan(p, `exd6`), NewMoveFromNotation(p, `e5d6`))
+	expect.Eq(t, NewMoveFromSan(p, `exd6`).capture(), Piece(BlackPawn))
+}
+
+// Chess960 castles are king-takes-rook in UCI notation.
+func TestMove500(t *testing.T) {
+	defer func(saved Engine) { engine = saved }(engine)
+	engine.chess960 = true
+
+	p, _ := ParseFEN(`1r2k1r1/1p4p1/8/8/8/8/1P4P1/1R2K1R1 w GBgb - 0 1`)
+	castle := NewMoveFromNotation(p, `e1g1`)
+	expect.Eq(t, castle, NewCastle(p, E1, G1))
+	expect.Eq(t, castle.notation(), `e1g1`)
+	expect.Eq(t, NewMoveFromNotation(p, `e1b1`), NewCastle(p, E1, C1))
+	expect.Eq(t, NewMoveFromNotation(p, `e1b1`).notation(), `e1b1`)
+	expect.Eq(t, NewMoveFromSan(p, `O-O-O`), NewCastle(p, E1, C1))
+	expect.Eq(t, NewMoveFromSan(p, `O-O-O`).String(), `0-0-0`)
+
+	p, _ = ParseFEN(`4k3/8/8/8/8/8/8/5KR1 w G - 0 1`)
+	expect.Eq(t, NewMoveFromNotation(p, `f1g1`), NewCastle(p, F1, G1))
+	expect.Eq(t, NewMoveFromSan(p, `O-O`), NewCastle(p, F1, G1))
+	expect.Eq(t, NewMoveFromSan(p, `Kg2`), NewMove(p, F1, G2))
+	expect.Eq(t, NewCastle(p, F1, G1).notation(), `f1g1`)
+}
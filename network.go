@@ -0,0 +1,171 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`bufio`
+	`encoding/binary`
+	`fmt`
+	`io`
+	`os`
+)
+
+const (
+	networkInputs = 12 * 64 // Piece type and color times square.
+	networkHidden = 32      // Accumulator size for each side.
+	networkQA     = 255     // Quantization of the first layer and clipping threshold.
+	networkQB     = 64      // Quantization of the output layer.
+	networkScale  = 400     // Output scale to convert network value to centipawns.
+)
+
+// Efficiently updatable neural network: 768 inputs (one for each piece on each
+// square) get transformed into two hidden layer accumulators, one for each side
+// perspective, followed by clipped ReLU and single output.
+type Network struct {
+	inputWeights  [networkInputs][networkHidden]int16 // First layer weights.
+	inputBias     [networkHidden]int16                // First layer bias.
+	outputWeights [2 * networkHidden]int16            // Side to move first, then the opponent.
+	outputBias    int32                               // Output bias.
+}
+
+// First layer values from White's and Black's perspective. The accumulator is
+// part of the position and gets updated incrementally when making a move.
+type Accumulator [2][networkHidden]int16
+
+// Loaded network weights, if any. When not nil the position accumulators
+// get updated while making moves.
+var network *Network
+
+// Loads network weights from the file that contains little-endian int16 input
+// weights, input bias, and output weights followed by int32 output bias.
+func NewNetwork(fileName string) (*Network, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	net := new(Network)
+	reader := bufio.NewReader(file)
+	for _, data := range net.layers() {
+		if err = binary.Read(reader, binary.LittleEndian, data); err != nil {
+			return nil, fmt.Errorf("invalid network file %s: %v", fileName, err)
+		}
+	}
+	if _, err = reader.ReadByte(); err != io.EOF {
+		return nil, fmt.Errorf("invalid network file %s: unexpected trailing data", fileName)
+	}
+
+	return net, nil
+}
+
+// Sets up network weights from the given file; empty file name unloads the
+// network.
+func LoadNetwork(fileName string) error {
+	if len(fileName) == 0 {
+		network = nil
+		return nil
+	}
+
+	net, err := NewNetwork(fileName)
+	if err == nil {
+		network = net
+	}
+
+	return err
+}
+
+// Saves network weights in the format expected by NewNetwork().
+func (net *Network) save(fileName string) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, data := range net.layers() {
+		if err = binary.Write(writer, binary.LittleEndian, data); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+// Returns pointers to network weights in the order they are stored in the file.
+func (net *Network) layers() []interface{} {
+	return []interface{}{ &net.inputWeights, &net.inputBias, &net.outputWeights, &net.outputBias }
+}
+
+// Returns network score for the position from the side to move point of view.
+func (net *Network) evaluate(p *Position) int {
+	output := net.outputBias
+	our, their := &p.accumulator[p.color], &p.accumulator[p.color^1]
+	for i := 0; i < networkHidden; i++ {
+		output += int32(crelu(our[i])) * int32(net.outputWeights[i])
+		output += int32(crelu(their[i])) * int32(net.outputWeights[networkHidden + i])
+	}
+
+	return int(output) * networkScale / (networkQA * networkQB)
+}
+
+// Computes accumulator values from scratch. When making a move the accumulator
+// gets updated incrementally.
+func (net *Network) refresh(p *Position) (acc Accumulator) {
+	acc[White], acc[Black] = net.inputBias, net.inputBias
+
+	board := p.board
+	for board.any() {
+		square := board.pop()
+		acc.add(net, p.pieces[square], square)
+	}
+
+	return acc
+}
+
+// Adds the piece on the square to both perspectives.
+func (acc *Accumulator) add(net *Network, piece Piece, square int) *Accumulator {
+	for color := White; color <= Black; color++ {
+		weights := &net.inputWeights[networkInput(uint8(color), piece, square)]
+		for i := range acc[color] {
+			acc[color][i] += weights[i]
+		}
+	}
+
+	return acc
+}
+
+// Removes the piece on the square from both perspectives.
+func (acc *Accumulator) sub(net *Network, piece Piece, square int) *Accumulator {
+	for color := White; color <= Black; color++ {
+		weights := &net.inputWeights[networkInput(uint8(color), piece, square)]
+		for i := range acc[color] {
+			acc[color][i] -= weights[i]
+		}
+	}
+
+	return acc
+}
+
+// Returns input index of the piece on the square as seen by the given side:
+// the board is flipped for Black so that own pieces always come first and
+// move up the board.
+func networkInput(color uint8, piece Piece, square int) int {
+	if color == Black {
+		square ^= 56
+	}
+	return ((piece.id() - 1) * 2 + int(piece.color() ^ color)) * 64 + square
+}
+
+// Clipped ReLU activation.
+func crelu(value int16) int16 {
+	if value < 0 {
+		return 0
+	} else if value > networkQA {
+		return networkQA
+	}
+	return value
+}
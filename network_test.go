@@ -0,0 +1,106 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `io/ioutil`; `os`; `path/filepath`; `testing`)
+
+// Writes network with pseudo-random weights to temporary file.
+func networkFile(t *testing.T, dir string) string {
+	net, seed := new(Network), uint32(2016)
+	random := func(limit int) int16 {
+		seed = seed * 1664525 + 1013904223
+		return int16(int(seed >> 16) % (2 * limit + 1) - limit)
+	}
+	for i := range net.inputWeights {
+		for j := range net.inputWeights[i] {
+			net.inputWeights[i][j] = random(32)
+		}
+	}
+	for i := range net.inputBias {
+		net.inputBias[i] = random(64)
+	}
+	for i := range net.outputWeights {
+		net.outputWeights[i] = random(64)
+	}
+	net.outputBias = 1000
+
+	fileName := filepath.Join(dir, `test.nnue`)
+	if err := net.save(fileName); err != nil {
+		t.Fatal(err)
+	}
+
+	return fileName
+}
+
+// Loading network weights.
+func TestNetwork000(t *testing.T) {
+	dir, _ := ioutil.TempDir(``, `network`)
+	defer os.RemoveAll(dir)
+	defer LoadNetwork(``)
+
+	fileName := networkFile(t, dir)
+	expect.True(t, LoadNetwork(fileName) == nil)
+	expect.True(t, network != nil)
+	expect.Eq(t, network.outputBias, int32(1000))
+
+	content, _ := ioutil.ReadFile(fileName)
+	ioutil.WriteFile(fileName, content[:len(content) - 1], 0644)
+	expect.True(t, LoadNetwork(fileName) != nil)
+	ioutil.WriteFile(fileName, append(content, 0), 0644)
+	expect.True(t, LoadNetwork(fileName) != nil)
+	expect.True(t, LoadNetwork(filepath.Join(dir, `missing.nnue`)) != nil)
+	expect.Eq(t, network.outputBias, int32(1000)) // Still loaded.
+
+	expect.True(t, LoadNetwork(``) == nil)
+	expect.True(t, network == nil)
+}
+
+func TestNetwork010(t *testing.T) {
+	expect.Eq(t, networkInput(White, Pawn, A2), A2)
+	expect.Eq(t, networkInput(Black, BlackPawn, A7), A2)
+	expect.Eq(t, networkInput(White, BlackPawn, A7), 64 + A7)
+	expect.Eq(t, networkInput(Black, Pawn, A2), 64 + A7)
+	expect.Eq(t, networkInput(White, King, E1), 10 * 64 + E1)
+	expect.Eq(t, networkInput(Black, King, E1), 11 * 64 + E8)
+	expect.Eq(t, crelu(-5), int16(0))
+	expect.Eq(t, crelu(300), int16(networkQA))
+}
+
+// Accumulator gets updated incrementally: castle, en-passant, capture,
+// and promotion.
+func TestNetwork020(t *testing.T) {
+	dir, _ := ioutil.TempDir(``, `network`)
+	defer os.RemoveAll(dir)
+	defer LoadNetwork(``)
+
+	LoadNetwork(networkFile(t, dir))
+	p := NewGame(`Ke1,Rh1,Nc3,e5,b7`, `Kg8,Rc8,d7,a5`).start()
+	expect.Eq(t, p.accumulator, network.refresh(p))
+
+	for _, move := range []string{ `e1g1`, `d7d5`, `e5d6`, `c8c3`, `b7b8q` } {
+		p = p.makeMove(NewMoveFromNotation(p, move))
+		expect.Eq(t, p.accumulator, network.refresh(p))
+	}
+	expect.Eq(t, p.pieces[B8], Piece(Queen))
+	expect.Eq(t, p.pieces[D5], Piece(0))
+}
+
+// Network evaluation gets used when enabled, and it is symmetric.
+func TestNetwork030(t *testing.T) {
+	dir, _ := ioutil.TempDir(``, `network`)
+	defer os.RemoveAll(dir)
+	defer NewEngine()
+
+	classic := NewGame(`Ke1,Nc3,e4`, `Kg8,d5`).start().Evaluate()
+	NewEngine(`network`, networkFile(t, dir))
+	expect.True(t, engine.useNetwork)
+
+	score := NewGame(`Ke1,Nc3,e4`, `Kg8,d5`).start().Evaluate()
+	expect.Ne(t, score, classic)
+	expect.Eq(t, NewGame(`Kg1,d4`, `M,Ke8,Nc6,e5`).start().Evaluate(), score)
+
+	engine.useNetwork = false
+	expect.Eq(t, NewGame(`Ke1,Nc3,e4`, `Kg8,d5`).start().Evaluate(), classic)
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`bufio`
+	`fmt`
+	`io`
+	`os`
+	`strconv`
+	`strings`
+)
+
+// Named evaluation parameter: the name of the variable in data_evaluate.go
+// and pointers to all the integer values it consists of.
+type Parameter struct {
+	name   string 		// Variable name, ex. "rookOnOpen".
+	values []*int 		// Midgame/endgame pairs for scores, plain values otherwise.
+}
+
+// Returns the list of evaluation parameters that could be tuned or loaded
+// from the parameter file.
+func evaluationParameters() []Parameter {
+	return []Parameter{
+		{ `valuePawn`, paramScore(&valuePawn) },
+		{ `valueKnight`, paramScore(&valueKnight) },
+		{ `valueBishop`, paramScore(&valueBishop) },
+		{ `valueRook`, paramScore(&valueRook) },
+		{ `valueQueen`, paramScore(&valueQueen) },
+		{ `rightToMove`, paramScore(&rightToMove) },
+		{ `bishopPawn`, paramScore(&bishopPawn) },
+		{ `bishopBoxed`, paramScore(&bishopBoxed) },
+		{ `bishopDanger`, paramScore(&bishopDanger) },
+		{ `rookOnPawn`, paramScore(&rookOnPawn) },
+		{ `rookOnOpen`, paramScore(&rookOnOpen) },
+		{ `rookOnSemiOpen`, paramScore(&rookOnSemiOpen) },
+		{ `rookOn7th`, paramScore(&rookOn7th) },
+		{ `rookBoxed`, paramScore(&rookBoxed) },
+		{ `behindPawn`, paramScore(&behindPawn) },
+		{ `hangingAttack`, paramScore(&hangingAttack) },
+		{ `kingAttack`, paramScore(&kingAttack) },
+		{ `kingByPawn`, paramScore(&kingByPawn) },
+		{ `pawnAlone`, paramScore(&pawnAlone) },
+		{ `spaceSquare`, paramScore(&spaceSquare) },
+		{ `knightOutpost`, paramScore(&knightOutpost) },
+		{ `bishopOutpost`, paramScore(&bishopOutpost) },
+		{ `rookConnected`, paramScore(&rookConnected) },
+		{ `queenBattery`, paramScore(&queenBattery) },
+		{ `pawnMajority`, paramScore(&pawnMajority) },
+		{ `weightMobility`, paramScore(&weightMobility) },
+		{ `weightPawnStructure`, paramScore(&weightPawnStructure) },
+		{ `weightPassedPawns`, paramScore(&weightPassedPawns) },
+		{ `weightSafety`, paramScore(&weightSafety) },
+		{ `weightCenter`, paramScore(&weightCenter) },
+		{ `weightThreats`, paramScore(&weightThreats) },
+		{ `bonusPawn`, paramInts(bonusPawn[0][:], bonusPawn[1][:]) },
+		{ `bonusKnight`, paramInts(bonusKnight[0][:], bonusKnight[1][:]) },
+		{ `bonusBishop`, paramInts(bonusBishop[0][:], bonusBishop[1][:]) },
+		{ `bonusRook`, paramInts(bonusRook[0][:], bonusRook[1][:]) },
+		{ `bonusQueen`, paramInts(bonusQueen[0][:], bonusQueen[1][:]) },
+		{ `bonusKing`, paramInts(bonusKing[0][:], bonusKing[1][:]) },
+		{ `bonusPassedPawn`, paramScores(bonusPassedPawn[:]) },
+		{ `bonusSemiPassedPawn`, paramScores(bonusSemiPassedPawn[:]) },
+		{ `bonusCandidatePawn`, paramScores(bonusCandidatePawn[:]) },
+		{ `extraPassedPawn`, paramInts(extraPassedPawn[:]) },
+		{ `lockedScale`, []*int{ &lockedScale } },
+		{ `extraKnight`, paramInts(extraKnight[:]) },
+		{ `extraBishop`, paramInts(extraBishop[:]) },
+		{ `bonusPawnThreat`, paramScores(bonusPawnThreat[:]) },
+		{ `bonusMinorThreat`, paramScores(bonusMinorThreat[:]) },
+		{ `bonusRookThreat`, paramScores(bonusRookThreat[:]) },
+		{ `kingThreat`, paramInts(kingThreat[:]) },
+		{ `kingSafety`, paramInts(kingSafety[:]) },
+		{ `safeCheck`, paramInts(safeCheck[:]) },
+		{ `queenContact`, []*int{ &queenContact } },
+		{ `kingFileDanger`, paramInts(kingFileDanger[:]) },
+		{ `stormFile`, paramInts(stormFile[:]) },
+		{ `penaltyCover`, paramInts(penaltyCover[:]) },
+		{ `penaltyStorm`, paramInts(penaltyStorm[:]) },
+		{ `penaltyStormBlocked`, paramInts(penaltyStormBlocked[:]) },
+		{ `penaltyStormUnblocked`, paramInts(penaltyStormUnblocked[:]) },
+		{ `penaltyPawnThreat`, paramScores(penaltyPawnThreat[:]) },
+		{ `penaltyDoubledPawn`, paramScores(penaltyDoubledPawn[:]) },
+		{ `penaltyIsolatedPawn`, paramScores(penaltyIsolatedPawn[:]) },
+		{ `penaltyWeakIsolatedPawn`, paramScores(penaltyWeakIsolatedPawn[:]) },
+		{ `penaltyBackwardPawn`, paramScores(penaltyBackwardPawn[:]) },
+		{ `penaltyWeakBackwardPawn`, paramScores(penaltyWeakBackwardPawn[:]) },
+		{ `mobilityKnight`, paramScores(mobilityKnight[:]) },
+		{ `mobilityBishop`, paramScores(mobilityBishop[:]) },
+		{ `mobilityRook`, paramScores(mobilityRook[:]) },
+		{ `mobilityQueen`, paramScores(mobilityQueen[:]) },
+	}
+}
+
+// Built-in parameter values to start with when loading the parameter file.
+var defaultParameters = snapshotParameters(evaluationParameters())
+
+// Loads evaluation parameters from the file that consists of "name = [ values ]"
+// lines as written by writeParameters(). Parameters missing in the file keep
+// their default values, and the empty file name restores all the defaults.
+func LoadParameters(fileName string) error {
+	params := evaluationParameters()
+	values := snapshotParameters(params)
+	restoreParameters(params, defaultParameters)
+
+	if len(fileName) > 0 {
+		if err := readParameters(fileName, params); err != nil {
+			restoreParameters(params, values)
+			return err
+		}
+	}
+
+	rebuildParameters()
+	materialBase = [len(materialBase)]MaterialEntry{}
+	initMaterial()
+
+	return nil
+}
+
+// Parses the parameter file and updates matching evaluation parameters.
+func readParameters(fileName string, params []Parameter) error {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	index := make(map[string]Parameter, len(params))
+	for _, param := range params {
+		index[param.name] = param
+	}
+
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		pair := strings.SplitN(line, `=`, 2)
+		if len(pair) != 2 {
+			return fmt.Errorf("%s:%d: expected name = [ values ]", fileName, lineNo)
+		}
+
+		name := strings.TrimSpace(pair[0])
+		param, ok := index[name]
+		if !ok {
+			return fmt.Errorf("%s:%d: unknown parameter %s", fileName, lineNo, name)
+		}
+
+		fields := strings.FieldsFunc(strings.Trim(strings.TrimSpace(pair[1]), `[]`), func(char rune) bool {
+			return char == ',' || char == ' ' || char == '\t'
+		})
+		if len(fields) != len(param.values) {
+			return fmt.Errorf("%s:%d: %s expects %d values, got %d", fileName, lineNo, name, len(param.values), len(fields))
+		}
+		for i, field := range fields {
+			value, err := strconv.Atoi(field)
+			if err != nil {
+				return fmt.Errorf("%s:%d: invalid %s value %s", fileName, lineNo, name, field)
+			}
+			*param.values[i] = value
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Returns the copy of current parameter values.
+func snapshotParameters(params []Parameter) (values [][]int) {
+	for _, param := range params {
+		list := make([]int, len(param.values))
+		for i, value := range param.values {
+			list[i] = *value
+		}
+		values = append(values, list)
+	}
+	return
+}
+
+// Sets parameter values from the snapshot.
+func restoreParameters(params []Parameter, values [][]int) {
+	for i, param := range params {
+		for j, value := range param.values {
+			*value = values[i][j]
+		}
+	}
+}
+
+// Rebuilds the tables that depend on evaluation parameters after they have
+// been changed.
+func rebuildParameters() {
+	pieceValue = [7]int{
+		0, valuePawn.midgame, valueKnight.midgame, valueBishop.midgame, valueRook.midgame, valueQueen.midgame, 0,
+	}
+	pst = [14][64]Score{}
+	initPST()
+
+	// Cached pawn structures and evaluation scores are based on the old
+	// values.
+	if engine.pawnCache != nil {
+		engine.pawnCache.clear()
+	}
+	for i := 0; i < len(game.evalCache); i++ {
+		game.evalCache[i] = EvalEntry{}
+	}
+}
+
+// Writes evaluation parameters as "name = [ value, ... ]" lines.
+func writeParameters(writer io.Writer, params []Parameter) error {
+	buffer := bufio.NewWriter(writer)
+	fmt.Fprintf(buffer, "# Donna v%s evaluation parameters.\n", Version)
+	for _, param := range params {
+		fmt.Fprintf(buffer, "%s = [", param.name)
+		for i, value := range param.values {
+			if i > 0 {
+				buffer.WriteString(`,`)
+			}
+			fmt.Fprintf(buffer, " %d", *value)
+		}
+		buffer.WriteString(" ]\n")
+	}
+
+	return buffer.Flush()
+}
+
+// Saves evaluation parameters to the given file.
+func saveParameters(fileName string, params []Parameter) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return writeParameters(file, params)
+}
+
+// Returns midgame/endgame value pointers of the score.
+func paramScore(value *Score) []*int {
+	return []*int{ &value.midgame, &value.endgame }
+}
+
+// Flattens score slices into the list of midgame/endgame value pointers.
+func paramScores(slices ...[]Score) (values []*int) {
+	for _, slice := range slices {
+		for i := range slice {
+			values = append(values, paramScore(&slice[i])...)
+		}
+	}
+	return
+}
+
+// Flattens integer slices into the list of value pointers.
+func paramInts(slices ...[]int) (values []*int) {
+	for _, slice := range slices {
+		for i := range slice {
+			values = append(values, &slice[i])
+		}
+	}
+	return
+}
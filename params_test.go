@@ -0,0 +1,108 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `bytes`; `io/ioutil`; `os`; `path/filepath`; `testing`)
+
+// Writes parameter file to temporary directory.
+func paramsFile(t *testing.T, dir, content string) string {
+	fileName := filepath.Join(dir, `test.params`)
+	if err := ioutil.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return fileName
+}
+
+// Loaded values override the defaults, and dependent tables get rebuilt.
+func TestParams000(t *testing.T) {
+	dir, _ := ioutil.TempDir(``, `params`)
+	defer os.RemoveAll(dir)
+	defer LoadParameters(``)
+
+	score := NewGame(`Ke1,Nb1,a2,b2,c2`, `Ke8,a7,b7,c7`).start().Evaluate()
+	fileName := paramsFile(t, dir, "# Stronger knight.\nvalueKnight = [ 1000, 1100 ]\n\nrookOnOpen = 30 5\n")
+	expect.True(t, LoadParameters(fileName) == nil)
+	expect.Eq(t, valueKnight, Score{1000, 1100})
+	expect.Eq(t, rookOnOpen, Score{30, 5})
+	expect.Eq(t, Piece(Knight).value(), 1000)
+	expect.True(t, NewGame(`Ke1,Nb1,a2,b2,c2`, `Ke8,a7,b7,c7`).start().Evaluate() > score)
+
+	// Defaults get restored.
+	expect.True(t, LoadParameters(``) == nil)
+	expect.Ne(t, valueKnight, Score{1000, 1100})
+	expect.Eq(t, rookOnOpen, Score{22, 10})
+	expect.Eq(t, NewGame(`Ke1,Nb1,a2,b2,c2`, `Ke8,a7,b7,c7`).start().Evaluate(), score)
+}
+
+// Invalid files leave the parameters intact.
+func TestParams010(t *testing.T) {
+	dir, _ := ioutil.TempDir(``, `params`)
+	defer os.RemoveAll(dir)
+	defer LoadParameters(``)
+
+	err := LoadParameters(paramsFile(t, dir, "rookOnOpen = [ 30, 5 ]\nrookOnMoon = [ 1, 2 ]\n"))
+	expect.True(t, err != nil)
+	expect.Contain(t, err.Error(), `:2: unknown parameter rookOnMoon`)
+	expect.Eq(t, rookOnOpen, Score{22, 10})
+
+	err = LoadParameters(paramsFile(t, dir, "rookOnOpen = [ 30 ]\n"))
+	expect.Contain(t, err.Error(), `rookOnOpen expects 2 values, got 1`)
+
+	err = LoadParameters(paramsFile(t, dir, "rookOnOpen = [ 30, x ]\n"))
+	expect.Contain(t, err.Error(), `invalid rookOnOpen value x`)
+
+	err = LoadParameters(paramsFile(t, dir, "rookOnOpen\n"))
+	expect.Contain(t, err.Error(), `expected name = [ values ]`)
+
+	err = LoadParameters(filepath.Join(dir, `missing.params`))
+	expect.True(t, err != nil)
+}
+
+// Dumped parameters load back unchanged.
+func TestParams020(t *testing.T) {
+	dir, _ := ioutil.TempDir(``, `params`)
+	defer os.RemoveAll(dir)
+	defer LoadParameters(``)
+
+	dump := new(bytes.Buffer)
+	writeParameters(dump, evaluationParameters())
+	expect.True(t, LoadParameters(paramsFile(t, dir, dump.String())) == nil)
+
+	reload := new(bytes.Buffer)
+	writeParameters(reload, evaluationParameters())
+	expect.Eq(t, reload.String(), dump.String())
+}
+
+// Engine option and UCI option.
+func TestParams030(t *testing.T) {
+	dir, _ := ioutil.TempDir(``, `params`)
+	defer os.RemoveAll(dir)
+	defer LoadParameters(``)
+
+	fileName := paramsFile(t, dir, "rookOnOpen = [ 30, 5 ]\n")
+	NewEngine(`params`, fileName)
+	expect.Eq(t, engine.paramsFile, fileName)
+	expect.Eq(t, rookOnOpen, Score{30, 5})
+
+	NewEngine(`params`, filepath.Join(dir, `missing.params`))
+	expect.Eq(t, engine.paramsFile, ``)
+}
+
+// Cached pawn structures and evaluation scores don't survive parameter changes.
+func TestParams040(t *testing.T) {
+	dir, _ := ioutil.TempDir(``, `params`)
+	defer os.RemoveAll(dir)
+	defer LoadParameters(``)
+	defer func() { engine.evalCache = 0 }()
+
+	engine.evalCache = 0.5
+	score := NewGame(`Ke1,c2,c3,d4`, `Ke8,c7,d6`).start().Evaluate()
+	expect.True(t, LoadParameters(paramsFile(t, dir, "penaltyDoubledPawn = [ 100, 100, 100, 100, 100, 100, 100, 100, 100, 100, 100, 100, 100, 100, 100, 100 ]\n")) == nil)
+	expect.True(t, NewGame(`Ke1,c2,c3,d4`, `Ke8,c7,d6`).start().Evaluate() < score)
+
+	expect.True(t, LoadParameters(``) == nil)
+	expect.Eq(t, NewGame(`Ke1,c2,c3,d4`, `Ke8,c7,d6`).start().Evaluate(), score)
+}
@@ -11,10 +11,10 @@ import (
 	`strings`
 )
 
-var tree [1024]Position
+var tree [1024]Position // Recent game positions followed by the search stack, see game.compact().
 var node, rootNode int
 
-type Position struct {		 // 224 bytes long.
+type Position struct {		 // 376 bytes long.
 	id           uint64      // Polyglot hash value for the position.
 	pawnId       uint64      // Polyglot hash value for position's pawn structure.
 	board        Bitmask     // Bitmask of all pieces on the board.
@@ -22,6 +22,7 @@ type Position struct {		 // 224 bytes long.
 	pieces       [64]Piece   // Array of 64 squares with pieces on them.
 	outposts     [14]Bitmask // Bitmasks of each piece on the board; [0] all white, [1] all black.
 	tally        Score       // Positional valuation score based on PST.
+	accumulator  Accumulator // Neural network hidden layer values.
 	balance      int 	 // Material balance index.
 	score        int         // Blended evaluation score.
 	reversible   bool        // Is this position reversible?
@@ -31,11 +32,73 @@ type Position struct {		 // 224 bytes long.
 	count50      uint8	 // 50 moves rule counter.
 }
 
+// Position setup error codes.
+const (
+	BadFormat = iota	// Missing or malformed FEN fields.
+	BadRank			// Wrong number of ranks or squares in a rank.
+	BadPiece		// Unknown piece or notation.
+	BadKings		// Missing king or more than one king of the same color.
+	BadPawns		// Pawn on the first or the last rank.
+	BadCastle		// Castle rights without king and rook on their squares.
+	BadEnpassant		// En-passant square that last move couldn't have caused.
+	BadCheck		// Side that is not to move is in check.
+)
+
+// Error returned by ParseFEN() and ParseDCF() for malformed or impossible
+// positions.
+type SetupError struct {
+	Code   int 		// One of Bad... codes above.
+	Input  string 		// Offending FEN field, DCF token, or entire position.
+}
+
+func (err *SetupError) Error() string {
+	reason := [...]string{
+		BadFormat:    `malformed position`,
+		BadRank:      `invalid rank`,
+		BadPiece:     `invalid piece`,
+		BadKings:     `each side must have exactly one king`,
+		BadPawns:     `pawns can't be on the first or last rank`,
+		BadCastle:    `invalid castle rights`,
+		BadEnpassant: `invalid en-passant square`,
+		BadCheck:     `side not to move is in check`,
+	}[err.Code]
+
+	return fmt.Sprintf("%s '%s'", reason, err.Input)
+}
+
+// Creates new position from Donna chess format strings. Unlike ParseDCF()
+// the position is not checked for legality, and nil is returned only if the
+// notation is malformed.
 func NewPosition(game *Game, white, black string) *Position {
+	p, _ := decodeDCF(white, black)
+	return p
+}
+
+// Decodes Donna chess format strings for White and Black, and creates new
+// position making sure it is legal.
+func ParseDCF(white, black string) (*Position, error) {
+	p, err := decodeDCF(white, black)
+	if err == nil {
+		err = p.validate(white + ` : ` + black)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Decodes Donna chess format strings without checking position legality.
+func decodeDCF(white, black string) (*Position, error) {
 	tree[node] = Position{}
 	p := &tree[node]
 
-	p.setupSide(white, White).setupSide(black, Black)
+	if err := p.setupSide(white, White); err != nil {
+		return nil, err
+	}
+	if err := p.setupSide(black, Black); err != nil {
+		return nil, err
+	}
 
 	p.castles = castleKingside[White] | castleQueenside[White] | castleKingside[Black] | castleQueenside[Black]
 	if p.pieces[E1] != King || p.pieces[H1] != Rook {
@@ -50,25 +113,9 @@ func NewPosition(game *Game, white, black string) *Position {
 	if p.pieces[E8] != BlackKing || p.pieces[A8] != BlackRook {
 		p.castles &= ^castleQueenside[Black]
 	}
+	setupCastles([2]int{ E1, E8 }, [2][2]int{ { H1, A1 }, { H8, A8 } })
 
-	for square, piece := range p.pieces {
-		if !piece.nil() {
-			p.outposts[piece].set(square)
-			p.outposts[piece.color()].set(square)
-			if piece.isKing() {
-				p.king[piece.color()] = uint8(square)
-			}
-			p.balance += materialBalance[piece]
-		}
-	}
-
-	p.reversible = true
-	p.board = p.outposts[White] | p.outposts[Black]
-	p.id, p.pawnId = p.polyglot()
-	p.tally = p.valuation()
-	p.score = Unknown
-
-	return p
+	return p.setup(), nil
 }
 
 // Parses Donna chess format string for one side. Besides [K]ing, [Q]ueen, [R]ook,
@@ -89,19 +136,20 @@ func NewPosition(game *Game, white, black string) *Position {
 // [E]npassant: specifies en-passant square if any. For example, "Ed3" marks D3
 //              square as en-passant. Default value is no en-passant.
 //
-func (p *Position) setupSide(str string, color uint8) *Position {
-	invalid := func (move string, color uint8) {
-		// Don't panic.
-		panic(fmt.Sprintf("Invalid notation '%s' for %s\n", move, C(color)))
-	}
-
+func (p *Position) setupSide(str string, color uint8) error {
 	for _, move := range strings.Split(str, `,`) {
+		if move = strings.TrimSpace(move); len(move) == 0 {
+			return &SetupError{ BadPiece, str }
+		}
 		if move[0] == 'M' { // TODO: parse move number.
+			if _, err := strconv.Atoi(move[1:]); err != nil && len(move) > 1 {
+				return &SetupError{ BadPiece, move }
+			}
 			p.color = color
 		} else {
 			arr := reMove.FindStringSubmatch(move)
-			if len(arr) == 0 {
-				invalid(move, color)
+			if len(arr) == 0 || arr[0] != move {
+				return &SetupError{ BadPiece, move }
 			}
 			square := square(int(arr[3][0]-'1'), int(arr[2][0]-'a'))
 
@@ -131,7 +179,7 @@ func (p *Position) setupSide(str string, color uint8) *Position {
 		}
 	}
 
-	return p
+	return nil
 }
 
 // Sets up initial chess position.
@@ -139,8 +187,28 @@ func NewInitialPosition(game *Game) *Position {
 	return NewPositionFromFEN(game, `rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1`)
 }
 
-// Decodes FEN string and creates new position.
+// Decodes FEN string and creates new position. Unlike ParseFEN() the position
+// is not checked for legality, and nil is returned only if FEN is malformed.
 func NewPositionFromFEN(game *Game, fen string) *Position {
+	p, _ := decodeFEN(fen)
+	return p
+}
+
+// Decodes FEN string and creates new position making sure it is legal.
+func ParseFEN(fen string) (*Position, error) {
+	p, err := decodeFEN(fen)
+	if err == nil {
+		err = p.validate(fen)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Decodes FEN string without checking position legality.
+func decodeFEN(fen string) (*Position, error) {
 	tree[node] = Position{}
 	p := &tree[node]
 
@@ -149,97 +217,163 @@ func NewPositionFromFEN(game *Game, fen string) *Position {
 	// [1] - Color of side to move.
 	// [2] - Castle rights.
 	// [3] - En-passant square.
-	// [4] - Number of half-moves.
-	// [5] - Number of full moves.
-	matches := strings.Split(fen, ` `)
-	if len(matches) < 4 {
-		return nil
+	// [4] - Number of half-moves (optional).
+	// [5] - Number of full moves (optional).
+	matches := strings.Fields(fen)
+	if len(matches) < 4 || len(matches) > 6 {
+		return nil, &SetupError{ BadFormat, fen }
 	}
 
 	// [0] - Pieces (entire board).
-	sq := A8
-	for _, char := range(matches[0]) {
-		piece := Piece(0)
-		switch(char) {
-		case 'P':
-			piece = Pawn
-		case 'p':
-			piece = BlackPawn
-		case 'N':
-			piece = Knight
-		case 'n':
-			piece = BlackKnight
-		case 'B':
-			piece = Bishop
-		case 'b':
-			piece = BlackBishop
-		case 'R':
-			piece = Rook
-		case 'r':
-			piece = BlackRook
-		case 'Q':
-			piece = Queen
-		case 'q':
-			piece = BlackQueen
-		case 'K':
-			piece = King
-			p.king[White] = uint8(sq)
-		case 'k':
-			piece = BlackKing
-			p.king[Black] = uint8(sq)
-		case '/':
-			sq -= 16
-		case '1', '2', '3', '4', '5', '6', '7', '8':
-			sq += int(char - '0')
+	ranks := strings.Split(matches[0], `/`)
+	if len(ranks) != 8 {
+		return nil, &SetupError{ BadRank, matches[0] }
+	}
+	for i, rank := range ranks {
+		sq, count := square(7 - i, 0), 0
+		for _, char := range rank {
+			if char >= '1' && char <= '8' {
+				count += int(char - '0')
+			} else if index := strings.IndexRune(`PpNnBbRrQqKk`, char); index < 0 {
+				return nil, &SetupError{ BadPiece, string(char) }
+			} else if count < 8 {
+				p.pieces[sq + count] = Piece(index + Pawn)
+				count++
+			} else {
+				count = 9 // Too many pieces.
+			}
 		}
-		if !piece.nil() {
-			p.pieces[sq] = piece
-			p.outposts[piece].set(sq)
-			p.outposts[piece.color()].set(sq)
-			p.balance += materialBalance[piece]
-			sq++
+		if count != 8 {
+			return nil, &SetupError{ BadRank, rank }
 		}
 	}
 
 	// [1] - Color of side to move.
-	p.color = uint8(let(matches[1] == `w`, White, Black))
+	switch matches[1] {
+	case `w`:
+		p.color = White
+	case `b`:
+		p.color = Black
+	default:
+		return nil, &SetupError{ BadFormat, matches[1] }
+	}
 
-	// [2] - Castle rights.
-	for _, char := range(matches[2]) {
-		switch(char) {
-		case 'K':
-			p.castles |= castleKingside[White]
-		case 'Q':
-			p.castles |= castleQueenside[White]
-		case 'k':
-			p.castles |= castleKingside[Black]
-		case 'q':
-			p.castles |= castleQueenside[Black]
-		case '-':
-			// No castling rights.
+	// [2] - Castle rights: KQkq, or Chess960 rook files in Shredder-FEN
+	// and X-FEN notations. KQkq refer to the outermost rooks.
+	kings, rooks := [2]int{ E1, E8 }, [2][2]int{ { H1, A1 }, { H8, A8 } }
+	if matches[2] != `-` {
+		for _, char := range(matches[2]) {
+			color := uint8(let(char >= 'a', Black, White))
+			home := A1 + 56 * int(color)
+
+			ours, king, rook := king(color), home, -1
+			for king < home + 8 && p.pieces[king] != ours {
+				king++
+			}
+			switch letter, castle := char &^ 0x20, Piece(Rook | color); {
+			case letter == 'K':
+				for square := home + 7; rook < 0 && square > king; square-- {
+					rook = let(p.pieces[square] == castle, square, -1)
+				}
+			case letter == 'Q':
+				for square := home; rook < 0 && square < king; square++ {
+					rook = let(p.pieces[square] == castle, square, -1)
+				}
+			case letter >= 'A' && letter <= 'H':
+				square := home + int(letter - 'A')
+				rook = let(p.pieces[square] == castle, square, -1)
+			}
+
+			if king == home + 8 || rook < 0 || rook == king {
+				return nil, &SetupError{ BadCastle, matches[2] }
+			}
+			side := let(rook > king, 0, 1)
+			castle := let(side == 0, int(castleKingside[color]), int(castleQueenside[color]))
+			if p.castles & uint8(castle) != 0 {
+				return nil, &SetupError{ BadCastle, matches[2] }
+			}
+			p.castles |= uint8(castle)
+			kings[color], rooks[color][side] = king, rook
 		}
 	}
+	setupCastles(kings, rooks)
 
 	// [3] - En-passant square.
 	if matches[3] != `-` {
-		p.enpassant = uint8(square(int(matches[3][1] - '1'), int(matches[3][0] - 'a')))
-
+		arr := reMove.FindStringSubmatch(matches[3])
+		if len(arr) == 0 || arr[0] != matches[3] || len(arr[1]) > 0 {
+			return nil, &SetupError{ BadEnpassant, matches[3] }
+		}
+		p.enpassant = uint8(square(int(arr[3][0] - '1'), int(arr[2][0] - 'a')))
 	}
 
 	// [4] - Number of half-moves.
-	if n, err := strconv.Atoi(matches[4]); err == nil {
+	if len(matches) > 4 {
+		n, err := strconv.Atoi(matches[4])
+		if err != nil || n < 0 || n > 255 {
+			return nil, &SetupError{ BadFormat, matches[4] }
+		}
 		p.count50 = uint8(n)
 	}
 
+	// [5] - Number of full moves.
+	if len(matches) > 5 {
+		if n, err := strconv.Atoi(matches[5]); err != nil || n < 1 {
+			return nil, &SetupError{ BadFormat, matches[5] }
+		}
+	}
+
+	return p.setup(), nil
+}
+
+// Builds piece bitmasks and hash values for the pieces on the board.
+func (p *Position) setup() *Position {
+	for square, piece := range p.pieces {
+		if !piece.nil() {
+			p.outposts[piece].set(square)
+			p.outposts[piece.color()].set(square)
+			if piece.isKing() {
+				p.king[piece.color()] = uint8(square)
+			}
+			p.balance += materialBalance[piece]
+		}
+	}
+
 	p.reversible = true
 	p.board = p.outposts[White] | p.outposts[Black]
 	p.id, p.pawnId = p.polyglot()
 	p.tally = p.valuation()
+	if network != nil {
+		p.accumulator = network.refresh(p)
+	}
 	p.score = Unknown
 
 	return p
 }
 
+// Makes sure the position could have occurred in the game. Last move must
+// have been a double pawn push for the en-passant square to be set.
+func (p *Position) validate(input string) error {
+	if p.outposts[King].count() != 1 || p.outposts[BlackKing].count() != 1 {
+		return &SetupError{ BadKings, input }
+	}
+	if pawns := p.outposts[Pawn] | p.outposts[BlackPawn]; (pawns & (maskRank[0] | maskRank[7])).any() {
+		return &SetupError{ BadPawns, input }
+	}
+	if p.enpassant != 0 {
+		square := int(p.enpassant)
+		if rank(p.color, square) != 5 || p.pieces[square - up[p.color]] != pawn(p.color^1) ||
+		   p.board.on(square) || p.board.on(square + up[p.color]) {
+			return &SetupError{ BadEnpassant, squareName(square) }
+		}
+	}
+	if p.isInCheck(p.color^1) {
+		return &SetupError{ BadCheck, input }
+	}
+
+	return nil
+}
+
 // Computes initial values of position's polyglot hash and pawn hash. When
 // making a move these values get updated incrementally.
 func (p *Position) polyglot() (hash, pawnHash uint64) {
@@ -291,20 +425,23 @@ func (p *Position) status(move Move, blendedScore int) int {
 		defer func() { p = p.undoLastMove() }()
 	}
 
-	switch ply, score := ply(), abs(blendedScore); score {
-	case 0:
-		if ply == 1 {
-			if p.insufficient() {
-				return Insufficient
-			} else if p.thirdRepetition() {
-				return Repetition
-			} else if p.fifty() {
-				return FiftyMoves
-			}
-		}
-		if !NewGen(p, MaxPly).generateMoves().anyValid() {
-			return Stalemate
+	// Draws are recognized by the position itself rather than by the score
+	// since contempt moves the draw score away from zero.
+	ply, score := ply(), abs(blendedScore)
+	if ply == 1 {
+		if p.insufficient() {
+			return Insufficient
+		} else if p.thirdRepetition() {
+			return Repetition
+		} else if p.fifty() {
+			return FiftyMoves
 		}
+	}
+	if !p.isInCheck(p.color) && !NewGen(p, MaxPly).generateMoves().anyValid() {
+		return Stalemate
+	}
+
+	switch score {
 	case Checkmate - ply:
 		if p.isInCheck(p.color) {
 			return let(p.color == White, BlackWon, WhiteWon)
@@ -360,20 +497,20 @@ func (p *Position) fen() (fen string) {
 		fen += ` b`
 	}
 
-	// Castle rights for both sides, if any.
+	// Castle rights for both sides, if any. Chess960 rooks that are not on
+	// the standard squares are shown by their files.
 	if p.castles & 0x0F != 0 {
 		fen += ` `
-		if p.castles & castleKingside[White] != 0 {
-			fen += `K`
-		}
-		if p.castles & castleQueenside[White] != 0 {
-			fen += `Q`
-		}
-		if p.castles & castleKingside[Black] != 0 {
-			fen += `k`
-		}
-		if p.castles & castleQueenside[Black] != 0 {
-			fen += `q`
+		for _, color := range []uint8{ White, Black } {
+			for side, castle := range []uint8{ castleKingside[color], castleQueenside[color] } {
+				if p.castles & castle != 0 {
+					char := `KQ`[side]
+					if square := castleRookFrom[color][side]; square != [2]int{ H1, A1 }[side] + 56 * int(color) {
+						char = byte(col(square)) + 'A'
+					}
+					fen += string(char | color << 5) // Lowercase for Black.
+				}
+			}
 		}
 	} else {
 		fen += ` -`
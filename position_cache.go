@@ -4,7 +4,11 @@
 
 package donna
 
-import `unsafe`
+import (
+	`fmt`
+	`strings`
+	`unsafe`
+)
 
 const (
 	cacheNone  = uint8(0)
@@ -12,6 +16,7 @@ const (
 	cacheBeta  = uint8(2) // Lower bound.
 	cacheExact = uint8(cacheAlpha | cacheBeta)
 	cacheEntrySize = int(unsafe.Sizeof(CacheEntry{}))
+	evalEntrySize = int(unsafe.Sizeof(EvalEntry{}))
 )
 
 type CacheEntry struct {
@@ -25,6 +30,13 @@ type CacheEntry struct {
 
 type Cache []CacheEntry
 
+type EvalEntry struct {
+	id    uint32
+	score int32
+}
+
+type EvalCache []EvalEntry
+
 func cacheUsage() (hits int) {
 	for i := 0; i < len(game.cache); i++ {
 		if game.cache[i].id != uint32(0) {
@@ -69,6 +81,26 @@ func NewCache(megaBytes float64) Cache {
 	return nil
 }
 
+// Creates new or resets existing evaluation cache. The number of entries gets
+// rounded down to the power of two.
+func NewEvalCache(megaBytes float64) EvalCache {
+	if megaBytes > 0.0 {
+		cacheSize := int(1024 * 1024 * megaBytes) / evalEntrySize
+		for cacheSize & (cacheSize - 1) != 0 {
+			cacheSize &= cacheSize - 1
+		}
+		if cacheSize != len(game.evalCache) {
+			return make(EvalCache, cacheSize)
+		}
+		for i := 0; i < len(game.evalCache); i++ {
+			game.evalCache[i] = EvalEntry{}
+		}
+		return game.evalCache
+	}
+
+	return nil
+}
+
 func (p *Position) cache(move Move, score, depth, ply int, flags uint8) *Position {
 	if cacheSize := len(game.cache); cacheSize > 0 {
 		index := p.id & uint64(cacheSize - 1)
@@ -114,3 +146,41 @@ func (p *Position) cachedMove() Move {
 
 	return Move(0)
 }
+
+// Returns evaluation score of the position looking it up in the evaluation
+// cache first. Cache misses get evaluated and saved in the cache.
+func (p *Position) cachedEvaluation() int {
+	if cacheSize := len(game.evalCache); cacheSize > 0 {
+		game.evals++
+		entry := &game.evalCache[p.id & uint64(cacheSize - 1)]
+		if id := uint32(p.id >> 32); entry.id != id {
+			entry.id, entry.score = id, int32(p.Evaluate())
+		} else {
+			game.evalhits++
+		}
+		return int(entry.score)
+	}
+
+	return p.Evaluate()
+}
+
+// Returns evaluation cache hit rate in percents.
+func evalCacheHits() int {
+	if game.evals == 0 {
+		return 0
+	}
+
+	return game.evalhits * 100 / game.evals
+}
+
+// Returns evaluation and pawn cache statistics for the last search.
+func cacheStats() (stats string) {
+	if game.evals > 0 {
+		stats = fmt.Sprintf(`evalhits %d%% `, evalCacheHits())
+	}
+	if pawns := engine.pawnCache; pawns != nil && pawns.probes > 0 {
+		stats += fmt.Sprintf(`pawnhits %d%% pawncollisions %d`, pawns.hitRate(), pawns.collisions)
+	}
+
+	return strings.TrimSpace(stats)
+}
@@ -19,3 +19,52 @@ func TestCache000(t *testing.T) {
 	expect.Eq(t, cached.flags, uint8(cacheExact))
 	expect.Eq(t, cached.id, uint32(p.id >> 32))
 }
+
+// Evaluation cache size is rounded down to the power of two.
+func TestCache010(t *testing.T) {
+	defer func() { engine.evalCache = 0 }()
+
+	engine.evalCache = 1
+	expect.Eq(t, len(NewGame().evalCache), 1024 * 1024 / evalEntrySize)
+	engine.evalCache = 1.5
+	expect.Eq(t, len(NewGame().evalCache), 1024 * 1024 / evalEntrySize)
+	engine.evalCache = 0
+	expect.Eq(t, len(NewGame().evalCache), 0)
+}
+
+// Cached evaluation.
+func TestCache020(t *testing.T) {
+	defer func() { engine.evalCache = 0 }()
+
+	engine.evalCache = 0.5
+	p := NewGame(`r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3`).start()
+	score := p.cachedEvaluation()
+	expect.Eq(t, score, p.Evaluate())
+	expect.Eq(t, game.evals, 1)
+	expect.Eq(t, game.evalhits, 0)
+
+	p = p.makeNullMove()
+	expect.Eq(t, p.cachedEvaluation(), p.Evaluate())
+	p = p.undoNullMove()
+	expect.Eq(t, p.cachedEvaluation(), score)
+	expect.Eq(t, game.evals, 3)
+	expect.Eq(t, game.evalhits, 1)
+	expect.Eq(t, evalCacheHits(), 33)
+}
+
+// Search results stay the same with evaluation cache.
+func TestCache030(t *testing.T) {
+	defer func() { engine.evalCache = 0 }()
+
+	fen := `r1bq1rk1/pp2bppp/2n1pn2/3p4/2PP4/2N1PN2/PP3PPP/R2QKB1R w KQ - 0 8`
+	p := NewGame(fen).start()
+	game.getReady()
+	move, score := p.solve(4), p.search(-Checkmate, Checkmate, 4)
+
+	engine.evalCache = 0.5
+	p = NewGame(fen).start()
+	game.getReady()
+	expect.Eq(t, p.solve(4), move)
+	expect.Eq(t, p.search(-Checkmate, Checkmate, 4), score)
+	expect.True(t, game.evalhits > 0)
+}
@@ -18,6 +18,9 @@ func (p *Position) movePiece(piece Piece, from, to int) *Position {
 
 	// Update positional score.
 	p.tally.sub(pst[piece][from]).add(pst[piece][to])
+	if network != nil {
+		p.accumulator.sub(network, piece, from).add(network, piece, to)
+	}
 
 	return p
 }
@@ -36,6 +39,9 @@ func (p *Position) promotePawn(pawn Piece, from, to int, promo Piece) *Position
 
 	// Update positional score.
 	p.tally.sub(pst[pawn][from]).add(pst[promo][to])
+	if network != nil {
+		p.accumulator.sub(network, pawn, from).add(network, promo, to)
+	}
 
 	return p
 }
@@ -54,6 +60,9 @@ func (p *Position) capturePiece(capture Piece, from, to int) *Position {
 
 	// Update positional score.
 	p.tally.sub(pst[capture][to])
+	if network != nil {
+		p.accumulator.sub(network, capture, to)
+	}
 
 	return p
 }
@@ -73,6 +82,9 @@ func (p *Position) captureEnpassant(capture Piece, from, to int) *Position {
 
 	// Update positional score.
 	p.tally.sub(pst[capture][enpassant])
+	if network != nil {
+		p.accumulator.sub(network, capture, enpassant)
+	}
 
 	return p
 }
@@ -100,22 +112,26 @@ func (p *Position) makeMove(move Move) *Position {
 	}
 
 	if promo := move.promo(); promo == 0 {
-		pp.movePiece(piece, from, to)
+		// Chess960 castle might leave the king on its square, and moving
+		// the piece onto itself would take it off the board.
+		if from != to {
+			pp.movePiece(piece, from, to)
+		}
 
 		if piece.isKing() {
 			pp.king[color] = uint8(to)
 			if move.isCastle() {
 				pp.reversible = false
-				switch to {
-				case G1:
-					pp.movePiece(Rook, H1, F1)
-				case C1:
-					pp.movePiece(Rook, A1, D1)
-				case G8:
-					pp.movePiece(BlackRook, H8, F8)
-				case C8:
-					pp.movePiece(BlackRook, A8, D8)
+
+				// In Chess960 the king and the rook might land on each other's
+				// initial squares, so set their squares after both moves.
+				rook, side := rook(color), let(col(to) == 6, 0, 1)
+				square, target := castleRookFrom[color][side], to + let(side == 0, -1, 1)
+				if square != target {
+					pp.movePiece(rook, square, target)
 				}
+				pp.pieces[from], pp.pieces[square] = 0, 0
+				pp.pieces[to], pp.pieces[target] = piece, rook
 			}
 		} else if piece.isPawn() {
 			pp.count50, pp.reversible = 0, false
@@ -162,6 +178,9 @@ func (p *Position) makeNullMove() *Position {
 
 // Restores previous position effectively taking back the last move made.
 func (p *Position) undoLastMove() *Position {
+	if node == 0 {
+		game.restore()
+	}
 	if node > 0 {
 		node--
 	}
@@ -180,6 +199,39 @@ func (p *Position) isInCheck(color uint8) bool {
 	return p.isAttacked(color^1, int(p.king[color]))
 }
 
+// Returns true if the move checks enemy king. Cheaper than making the move
+// since only the attacks on the king square are looked at. Castles, promotions,
+// and en-passant captures are assumed to give check.
+func (p *Position) givesCheck(move Move) bool {
+	from, to, piece, capture := move.split()
+	if move.isCastle() || move.isPromo() || (capture != 0 && to != 0 && to == int(p.enpassant)) {
+		return true
+	}
+
+	color := piece.color()
+	square, board := int(p.king[color^1]), p.board ^ bit[from] | bit[to]
+
+	// Discovered check by the piece behind the one that has moved.
+	if (p.attackers(color, square, board) & ^bit[from]).any() {
+		return true
+	}
+
+	switch piece.kind() {
+	case Pawn:
+		return pawnAttacks[color][to].on(square)
+	case Knight:
+		return knightMoves[to].on(square)
+	case Bishop:
+		return p.bishopMovesAt(to, board).on(square)
+	case Rook:
+		return p.rookMovesAt(to, board).on(square)
+	case Queen:
+		return (p.bishopMovesAt(to, board) | p.rookMovesAt(to, board)).on(square)
+	}
+
+	return false
+}
+
 func (p *Position) isNull() bool {
 	return node > 0 && tree[node].board == tree[node-1].board
 }
@@ -189,15 +241,14 @@ func (p *Position) fifty() bool {
 }
 
 func (p *Position) repetition() bool {
-	if !p.reversible || node < 1 {
+	if !p.reversible || game.ply() < 1 {
 		return false
 	}
 
-	for previous := node - 1; previous >= 0; previous-- {
-		if !tree[previous].reversible {
+	for previous := node - 1; previous >= -len(game.past); previous-- {
+		if position := game.at(previous); !position.reversible {
 			return false
-		}
-		if tree[previous].id == p.id {
+		} else if position.id == p.id {
 			return true
 		}
 	}
@@ -206,15 +257,15 @@ func (p *Position) repetition() bool {
 }
 
 func (p *Position) thirdRepetition() bool {
-	if !p.reversible || node < 4 {
+	if !p.reversible || game.ply() < 4 {
 		return false
 	}
 
-	for previous, repetitions := node - 2, 1; previous >= 0; previous -= 2 {
-		if !tree[previous].reversible || !tree[previous + 1].reversible {
+	for previous, repetitions := node - 2, 1; previous >= -len(game.past); previous -= 2 {
+		if !game.at(previous).reversible || !game.at(previous + 1).reversible {
 			return false
 		}
-		if tree[previous].id == p.id {
+		if game.at(previous).id == p.id {
 			repetitions++
 			if repetitions == 3 {
 				return true
@@ -237,13 +288,58 @@ func (p *Position) canCastle(color uint8) (kingside, queenside bool) {
 	// final check.
 	if kingside || queenside {
 		attacks := p.allAttacks(color^1)
-		kingside = kingside && (castleKing[color] & attacks == 0)
-		queenside = queenside && (castleQueen[color] & attacks == 0)
+		kingside = kingside && (castleKing[color] & attacks == 0) && !p.shielded(color, 0)
+		queenside = queenside && (castleQueen[color] & attacks == 0) && !p.shielded(color, 1)
 	}
 
 	return kingside, queenside
 }
 
+// Returns true if the castle rook shields king's destination square from enemy
+// rook or queen on the home rank. That's only possible in Chess960 games, ex.
+// the rook on B1 and enemy queen on A1 when castling queenside.
+func (p *Position) shielded(color uint8, side int) bool {
+	square, target := castleRookFrom[color][side], let(side == 0, G1, C1) + 56 * int(color)
+	sliders := (p.outposts[rook(color^1)] | p.outposts[queen(color^1)]) & maskRank[row(target)]
+
+	return (p.rookMovesAt(target, p.board ^ bit[square]) & sliders).any()
+}
+
+// Sets up castle masks for the initial king and rook squares. Standard chess is
+// the special case of Chess960 with the king on E file and the rooks on A and
+// H files.
+func setupCastles(kings [2]int, rooks [2][2]int) {
+	castleKingFrom, castleRookFrom = kings, rooks
+	for square := range castleRights {
+		castleRights[square] = 0x0F
+	}
+
+	for color := White; color <= Black; color++ {
+		king, rank := kings[color], 56 * color
+		castleRights[king] &= ^(castleKingside[color] | castleQueenside[color])
+		castleRights[rooks[color][0]] &= ^castleKingside[color]
+		castleRights[rooks[color][1]] &= ^castleQueenside[color]
+
+		// Squares between the king and the rook and their destinations must
+		// be empty, and the squares the king passes must be safe.
+		kingside, rookside := homeSpan(king, G1 + rank), homeSpan(rooks[color][0], F1 + rank)
+		gapKing[color] = (kingside | rookside) & ^(bit[king] | bit[rooks[color][0]])
+		castleKing[color] = kingside
+
+		queenside, rookside := homeSpan(king, C1 + rank), homeSpan(rooks[color][1], D1 + rank)
+		gapQueen[color] = (queenside | rookside) & ^(bit[king] | bit[rooks[color][1]])
+		castleQueen[color] = queenside
+	}
+}
+
+// Returns the squares between and including the two squares on the same rank.
+func homeSpan(from, to int) (mask Bitmask) {
+	for square := min(from, to); square <= max(from, to); square++ {
+		mask.set(square)
+	}
+	return mask
+}
+
 // Returns a bitmask of all pinned pieces preventing a check for the king on
 // given square. The color of the pieces match the color of the king.
 func (p *Position) pins(square uint8) (bitmask Bitmask) {
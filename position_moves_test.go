@@ -613,3 +613,142 @@ func TestPositionMoves410(t *testing.T) {
 	expect.True(t, position.isInCheck(position.color))
 	expect.True(t, position.isInCheck(p.color^1))
 }
+
+// Game longer than the tree: earlier positions get moved out to the game
+// history and brought back when taking back the moves.
+func TestPositionMoves500(t *testing.T) {
+	p := NewGame().start()
+	initial := p.id
+
+	for i := 0; i < 300; i++ {
+		p = game.makeMove(NewMove(p, G1, F3))
+		p = game.makeMove(NewMove(p, G8, F6))
+		p = game.makeMove(NewMove(p, F3, G1))
+		p = game.makeMove(NewMove(p, F6, G8))
+	}
+	expect.Eq(t, game.ply(), 1200)
+	expect.True(t, node < len(tree))
+	expect.Eq(t, p.id, initial)
+	expect.True(t, p.thirdRepetition())
+
+	for i := 0; i < 1200; i++ {
+		p = p.undoLastMove()
+	}
+	expect.Eq(t, game.ply(), 0)
+	expect.Eq(t, len(game.past), 0)
+	expect.Eq(t, p.id, initial)
+	expect.Eq(t, p.fen(), `rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1`)
+}
+
+// Repetition detection looks past the tree into the game history.
+func TestPositionMoves510(t *testing.T) {
+	p := NewGame().start()
+	p = p.makeMove(NewMove(p, G1, F3))
+	p = p.makeMove(NewMove(p, G8, F6))
+	p = p.makeMove(NewMove(p, F3, G1))
+
+	// Move everything but the current position out of the tree.
+	game.past = append(game.past, tree[:node]...)
+	tree[0], node = tree[node], 0
+	p = &tree[0]
+
+	p = p.makeMove(NewMove(p, F6, G8))
+	expect.Eq(t, game.ply(), 4)
+	expect.True(t, p.repetition())
+	expect.False(t, p.thirdRepetition())
+
+	p = p.makeMove(NewMove(p, E2, E4)) // Irreversible.
+	p = p.makeMove(NewMove(p, G8, F6))
+	p = p.makeMove(NewMove(p, G1, F3))
+	p = p.makeMove(NewMove(p, F6, G8))
+	expect.False(t, p.repetition())
+}
+
+// Chess960 castles: the king takes the rook's square.
+func TestPositionMoves600(t *testing.T) {
+	p, _ := ParseFEN(`1r2k1r1/1p4p1/8/8/8/8/1P4P1/1R2K1R1 w GBgb - 0 1`)
+	kingside, queenside := p.canCastle(White)
+	expect.True(t, kingside)
+	expect.True(t, queenside)
+
+	p = p.makeMove(NewCastle(p, E1, G1))
+	expect.Eq(t, p.pieces[G1], Piece(King))
+	expect.Eq(t, p.pieces[F1], Piece(Rook))
+	expect.Eq(t, p.outposts[Rook], bit[B1]|bit[F1])
+	expect.Eq(t, p.outposts[White], bit[B1]|bit[F1]|bit[G1]|bit[B2]|bit[G2])
+	expect.Eq(t, p.fen(), `1r2k1r1/1p4p1/8/8/8/8/1P4P1/1R3RK1 b gb - 1 1`)
+
+	p = p.makeMove(NewCastle(p, E8, C8))
+	expect.Eq(t, p.pieces[C8], Piece(BlackKing))
+	expect.Eq(t, p.pieces[D8], Piece(BlackRook))
+	expect.Eq(t, p.pieces[B8], Piece(0))
+	expect.Eq(t, p.castles, uint8(0))
+
+	p = p.undoLastMove().undoLastMove()
+	expect.Eq(t, p.fen(), `1r2k1r1/1p4p1/8/8/8/8/1P4P1/1R2K1R1 w GBgb - 0 1`)
+}
+
+// The king and the rook swap their squares.
+func TestPositionMoves610(t *testing.T) {
+	p, _ := ParseFEN(`4k3/8/8/8/8/8/8/5KR1 w G - 0 1`)
+	p = p.makeMove(NewCastle(p, F1, G1))
+	expect.Eq(t, p.pieces[G1], Piece(King))
+	expect.Eq(t, p.pieces[F1], Piece(Rook))
+	expect.Eq(t, p.king[White], uint8(G1))
+	expect.Eq(t, p.outposts[White], bit[F1]|bit[G1])
+
+	hash, _ := p.polyglot()
+	expect.Eq(t, p.id, hash)
+}
+
+// Castle rook shields the king's destination from the enemy queen.
+func TestPositionMoves620(t *testing.T) {
+	p, _ := ParseFEN(`4k3/8/8/8/8/8/8/qR1K4 w B - 0 1`)
+	_, queenside := p.canCastle(White)
+	expect.False(t, queenside)
+
+	p, _ = ParseFEN(`4k3/8/8/8/8/8/q7/1R1K4 w B - 0 1`)
+	_, queenside = p.canCastle(White)
+	expect.True(t, queenside)
+}
+
+// King or rook already standing on its castle destination.
+func TestPositionMoves630(t *testing.T) {
+	for _, test := range []struct{ fen string; from, to, rook int; result string }{
+		{ `4k3/8/8/8/8/8/8/6KR w H - 0 1`, G1, G1, F1, `4k3/8/8/8/8/8/8/5RK1 b - - 1 1` },
+		{ `4k3/8/8/8/8/8/8/4KR2 w F - 0 1`, E1, G1, F1, `4k3/8/8/8/8/8/8/5RK1 b - - 1 1` },
+		{ `4k3/8/8/8/8/8/8/R1K5 w A - 0 1`, C1, C1, D1, `4k3/8/8/8/8/8/8/2KR4 b - - 1 1` },
+	} {
+		p, _ := ParseFEN(test.fen)
+		p = p.makeMove(NewCastle(p, test.from, test.to))
+		expect.Eq(t, p.outposts[King], bit[test.to])
+		expect.Eq(t, p.outposts[Rook], bit[test.rook])
+		expect.Eq(t, p.outposts[White], bit[test.to]|bit[test.rook])
+		expect.Eq(t, p.fen(), test.result)
+
+		hash, _ := p.polyglot()
+		expect.Eq(t, p.id, hash)
+	}
+}
+
+// Checks are detected without making the move.
+func TestPositionMoves700(t *testing.T) {
+	for _, fen := range []string{
+		`r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1`,
+		`8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1`,
+		`4k3/8/8/8/4N3/8/1B6/4R1K1 w - - 0 1`,
+		`3k4/8/8/2N5/8/8/6B1/Q3K3 w - - 0 1`,
+		`r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1`,
+	} {
+		p := NewGame(fen).start()
+		for _, move := range NewGen(p, MaxPly).generateAllMoves().validOnly().allMoves() {
+			check := p.makeMove(move).isInCheck(p.color^1)
+			p = p.undoLastMove()
+			if check {
+				expect.True(t, p.givesCheck(move))
+			} else if !move.isCastle() && !move.isPromo() {
+				expect.False(t, p.givesCheck(move))
+			}
+		}
+	}
+}
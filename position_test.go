@@ -139,6 +139,17 @@ func TestPosition230(t *testing.T) {
 	expect.Eq(t, p.status(NewMove(p, A1, B2), 0), Stalemate)
 }
 
+// Stalemate doesn't depend on the score, and neither does the game in progress
+// with the score that happens to match the draw score.
+func TestPosition235(t *testing.T) {
+	defer func(saved Engine) { engine = saved }(engine)
+
+	engine.contempt = 50
+	p := NewGame(`Kf7,b2,b4,h6`, `Kh8,Ba4,b3,b5,h7`).start()
+	expect.Eq(t, p.status(NewMove(p, F7, F8), 25), Stalemate)
+	expect.Eq(t, p.status(NewMove(p, F7, E7), abs(drawScore(Black))), InProgress)
+}
+
 // Draw by repetition.
 func TestPosition240(t *testing.T) {
 	p := NewGame(`Ka1,g3,h2`, `M,Kh5,h3,g4,g5,g6,h7`).start() // Initial.
@@ -183,3 +194,99 @@ func TestPosition310(t *testing.T) {
 	expect.Eq(t, p.Evaluate(), -320)
 
 }
+
+// Setup error code for the given FEN, or -1 if the position is valid.
+func fenError(fen string) int {
+	if _, err := ParseFEN(fen); err != nil {
+		return err.(*SetupError).Code
+	}
+	return -1
+}
+
+// Valid FEN, with and without move counters.
+func TestPosition400(t *testing.T) {
+	p, err := ParseFEN(`rnbqkbnr/pppp1ppp/8/8/3Pp3/8/PPP1PPPP/RNBQKBNR b KQkq d3 0 2`)
+	expect.Eq(t, err, nil)
+	expect.Eq(t, p.enpassant, uint8(D3))
+	expect.Eq(t, fenError(`4k3/8/8/8/8/8/8/4K3 w - -`), -1)
+}
+
+// Malformed FEN.
+func TestPosition410(t *testing.T) {
+	expect.Eq(t, fenError(``), BadFormat)
+	expect.Eq(t, fenError(`4k3/8/8/8/8/8/8/4K3 x - - 0 1`), BadFormat)
+	expect.Eq(t, fenError(`4k3/8/8/8/8/8/8/4K3 w - - x 1`), BadFormat)
+	expect.Eq(t, fenError(`4k3/8/8/8/8/8/8/4K3 w - - 0 1 extra`), BadFormat)
+	expect.Eq(t, fenError(`4k3/8/8/8/8/8/4K3 w - - 0 1`), BadRank)
+	expect.Eq(t, fenError(`4k3/8/8/8/8/8/54/4K3 w - - 0 1`), BadRank)
+	expect.Eq(t, fenError(`4k3/8/8/8/8/8/7/4K3 w - - 0 1`), BadRank)
+	expect.Eq(t, fenError(`4k3/8/8/8/8/8/pppppppp1/4K3 w - - 0 1`), BadRank)
+	expect.Eq(t, fenError(`4k3/8/8/8/8/8/7x/4K3 w - - 0 1`), BadPiece)
+	expect.Eq(t, fenError(`4k3/8/8/8/8/8/8/4K3 w - e9 0 1`), BadEnpassant)
+}
+
+// Impossible positions.
+func TestPosition420(t *testing.T) {
+	expect.Eq(t, fenError(`8/8/8/8/8/8/8/4K3 w - - 0 1`), BadKings)
+	expect.Eq(t, fenError(`4k3/8/8/8/8/8/8/3KK3 w - - 0 1`), BadKings)
+	expect.Eq(t, fenError(`4k3/8/8/8/8/8/8/P3K3 w - - 0 1`), BadPawns)
+	expect.Eq(t, fenError(`p3k3/8/8/8/8/8/8/4K3 w - - 0 1`), BadPawns)
+	expect.Eq(t, fenError(`4k3/8/8/8/8/8/8/4K3 w K - 0 1`), BadCastle)
+	expect.Eq(t, fenError(`4k3/8/8/8/8/8/8/R3K3 w QQ - 0 1`), BadCastle)
+	expect.Eq(t, fenError(`4k3/8/8/8/8/8/8/R3K3 w Qx - 0 1`), BadCastle)
+	expect.Eq(t, fenError(`4k3/8/8/8/3P4/8/8/4K3 w - d3 0 1`), BadEnpassant)
+	expect.Eq(t, fenError(`4k3/8/8/8/3P4/8/8/4K3 b - d6 0 1`), BadEnpassant)
+	expect.Eq(t, fenError(`4k3/8/8/8/8/8/8/4K2R w - - 0 1`), -1)
+	expect.Eq(t, fenError(`4k3/8/8/8/8/8/8/4K2R b - - 0 1`), -1)
+	expect.Eq(t, fenError(`4k2R/8/8/8/8/8/8/4K3 w - - 0 1`), BadCheck)
+}
+
+// Donna chess format.
+func TestPosition430(t *testing.T) {
+	p, err := ParseDCF(`Ke1,Rh1,e2`, `M,Ke8,d7`)
+	expect.Eq(t, err, nil)
+	expect.Eq(t, p.color, uint8(Black))
+	expect.Eq(t, p.castles, castleKingside[White])
+
+	_, err = ParseDCF(`Ke1,Xz9`, `Ke8`)
+	expect.Eq(t, err.(*SetupError).Code, BadPiece)
+	expect.Eq(t, err.Error(), `invalid piece 'Xz9'`)
+	_, err = ParseDCF(`Ke1,`, `Ke8`)
+	expect.Eq(t, err.(*SetupError).Code, BadPiece)
+	_, err = ParseDCF(`Ke1,a1`, `Ke8`)
+	expect.Eq(t, err.(*SetupError).Code, BadPawns)
+	_, err = ParseDCF(`Ke1,Qe2`, `Ke8`)
+	expect.Eq(t, err.(*SetupError).Code, BadCheck)
+	_, err = ParseDCF(`Ke1`, `M,Ke8,Ed6`)
+	expect.Eq(t, err.(*SetupError).Code, BadEnpassant)
+
+	// Lenient setup doesn't check legality but still rejects bad notation.
+	expect.True(t, NewGame(`Ke1,Qe2`, `Ke8`).start() != nil)
+	expect.True(t, NewGame(`Ke1,Xz9`, `Ke8`).start() == nil)
+}
+
+// Chess960 castle rights in Shredder-FEN and X-FEN.
+func TestPosition500(t *testing.T) {
+	p, err := ParseFEN(`1r2k1r1/8/8/8/8/8/8/1R2K1R1 w GBgb - 0 1`)
+	expect.Eq(t, err, nil)
+	expect.Eq(t, p.castles, uint8(0x0F))
+	expect.Eq(t, castleKingFrom, [2]int{ E1, E8 })
+	expect.Eq(t, castleRookFrom, [2][2]int{ { G1, B1 }, { G8, B8 } })
+	expect.Eq(t, p.fen(), `1r2k1r1/8/8/8/8/8/8/1R2K1R1 w GBgb - 0 1`)
+
+	// X-FEN: KQ refer to the outermost rooks.
+	p, _ = ParseFEN(`rr2k1r1/8/8/8/8/8/8/RR2K1R1 w KQkq - 0 1`)
+	expect.Eq(t, castleRookFrom, [2][2]int{ { G1, A1 }, { G8, A8 } })
+	expect.Eq(t, p.fen(), `rr2k1r1/8/8/8/8/8/8/RR2K1R1 w GQgq - 0 1`)
+
+	// Back to standard chess.
+	p, _ = ParseFEN(`r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1`)
+	expect.Eq(t, castleRookFrom, [2][2]int{ { H1, A1 }, { H8, A8 } })
+	expect.Eq(t, castleRights[E1], uint8(12))
+	expect.Eq(t, gapQueen[White], bit[B1]|bit[C1]|bit[D1])
+	expect.Eq(t, p.fen(), `r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1`)
+
+	expect.Eq(t, fenError(`1r2k1r1/8/8/8/8/8/8/1R2K1R1 w H - 0 1`), BadCastle)
+	expect.Eq(t, fenError(`1r2k1r1/8/8/8/8/8/8/1R2K1R1 w E - 0 1`), BadCastle)
+	expect.Eq(t, fenError(`1r2k1r1/8/8/8/8/8/8/1R2K1R1 w GK - 0 1`), BadCastle)
+}
@@ -0,0 +1,19 @@
+#!/usr/bin/env bash
+#
+usage() {
+  echo "Usage: ./wdl.sh \$1 \$2"
+  echo "Where \$1 - command to launch Donna"
+  echo "      \$2 - PGN file with self-play games and engine scores in move comments"
+  echo "Example:"
+  echo "\$ ./play.sh 1000 ../donna ../donna 40/60+1 /tmp/self-play.pgn ./nebula.epd"
+  echo "\$ ./wdl.sh ../donna /tmp/self-play.pgn"
+  echo
+}
+
+if [ $# -ne 2 ]; then
+  usage
+else
+  cmd="printf 'wdl %s\nexit\n' $2 | $1 -i"
+  echo $cmd
+  eval $cmd
+fi
@@ -94,7 +94,7 @@ func (p *Position) search(alpha, beta, depth int) (score int) {
 
 
 	if moveCount == 0 {
-		score = let(inCheck, -Checkmate, 0) // Mate if in check, stalemate otherwise.
+		score = let(inCheck, -Checkmate, drawScore(p.color)) // Mate if in check, stalemate otherwise.
 		if engine.uci {
 			engine.uciScore(depth, score, alpha, beta)
 		}
@@ -8,6 +8,8 @@ package donna
 func (p *Position) searchQuiescence(alpha, beta, depth int, inCheck bool) (score int) {
 	ply := ply()
 
+	engine.poll()
+
 	// Return if it's time to stop search.
 	if ply >= MaxPly || engine.clock.halt {
 		return p.Evaluate()
@@ -15,7 +17,7 @@ func (p *Position) searchQuiescence(alpha, beta, depth int, inCheck bool) (score
 
 	// Insufficient material and repetition/perpetual check pruning.
 	if p.fifty() || p.insufficient() || p.repetition() {
-		return 0
+		return drawScore(p.color)
 	}
 
 	// Checkmate distance pruning.
@@ -56,13 +58,13 @@ func (p *Position) searchQuiescence(alpha, beta, depth int, inCheck bool) (score
 	} else {
 		if cached != nil {
 			if p.score == Unknown {
-				p.score = p.Evaluate()
+				p.score = p.cachedEvaluation()
 			}
 		} else {
 			if isNull {
 				p.score = rightToMove.midgame * 2 - tree[node-1].score
 			} else {
-				p.score = p.Evaluate()
+				p.score = p.cachedEvaluation()
 			}
 		}
 		if p.score >= beta {
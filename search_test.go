@@ -175,6 +175,46 @@ func TestSearch300(t *testing.T) {
 	expect.Eq(t, move, `Ne5-f7`)
 }
 
+// Zugzwang.
+
+func TestSearch310(t *testing.T) {
+	move := NewGame(`8/8/p1p5/1p5p/1P5p/8/PPP2K1p/4R1rk w - - 0 1`).start().solve(7)
+	expect.Eq(t, move, `Re1-f1`)
+}
+
+func TestSearch320(t *testing.T) {
+	move := NewGame(`1q1k4/2Rr4/8/2Q3K1/8/8/8/8 w - - 0 1`).start().solve(5)
+	expect.Eq(t, move, `Kg5-h6`)
+}
+
+// Mutual zugzwang: whoever moves loses the pawn. The null move fails high
+// but the verification search without it fails low.
+func TestSearch325(t *testing.T) {
+	fen := `8/8/8/p1Kp3p/P2Pk2P/P6P/8/8 w - - 0 1`
+	p := NewGame(fen).start()
+	game.getReady()
+	expect.Eq(t, p.searchTree(99, 100, 10), 99)
+
+	// Same search trusting the null move.
+	p = NewGame(fen).start()
+	game.getReady()
+	game.nullPly, game.nullColor = 1, Black
+	expect.Eq(t, p.searchTree(99, 100, 10), 100)
+	game.nullPly = 0
+}
+
+func TestSearch330(t *testing.T) {
+	move := NewGame(`8/8/1p1r1k2/p1pPN1p1/P3KnP1/1P6/8/3R4 b - - 0 1`).start().solve(7)
+	expect.Eq(t, move, `Nf4xd5`)
+}
+
+func TestSearch340(t *testing.T) { // Adaptive null move reduction.
+	expect.Eq(t, nullMoveReduction(2, -50), 3)
+	expect.Eq(t, nullMoveReduction(6, 50), 4)
+	expect.Eq(t, nullMoveReduction(12, 250), 7)
+	expect.Eq(t, nullMoveReduction(12, 1000), 8)
+}
+
 // Perft.
 func TestSearch400(t *testing.T) {
 	position := NewGame().start()
@@ -205,3 +245,114 @@ func TestSearch450(t *testing.T) {
 	position := NewGame().start()
 	expect.Eq(t, position.Perft(5), int64(4865609))
 }
+
+// Contempt makes draws undesirable for the side to move at the root.
+func TestSearch500(t *testing.T) {
+	defer func(contempt int, analysis bool) { engine.contempt, engine.analysis = contempt, analysis }(engine.contempt, engine.analysis)
+
+	engine.contempt = 25
+	NewGame().start()
+	expect.Eq(t, drawScore(White), -25)
+	expect.Eq(t, drawScore(Black), 25)
+
+	engine.analysis = true
+	expect.Eq(t, drawScore(White), 0)
+	expect.Eq(t, drawScore(Black), 0)
+}
+
+// Fifty moves draw scored with contempt.
+func TestSearch510(t *testing.T) {
+	defer func(contempt int) { engine.contempt = contempt }(engine.contempt)
+
+	engine.contempt = 25
+	game := NewGame(`4k3/8/8/8/8/8/4P3/R3K3 b - - 100 80`)
+	p := game.start()
+	game.getReady()
+	expect.Eq(t, p.searchTree(-Checkmate, Checkmate, 1), -25)
+
+	engine.contempt = 0
+	expect.Eq(t, p.searchTree(-Checkmate, Checkmate, 1), 0)
+}
+
+// Chess960 perft.
+func TestSearch600(t *testing.T) {
+	position, _ := ParseFEN(`bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9`)
+	expect.Eq(t, position.Perft(4), int64(326672))
+}
+
+func TestSearch610(t *testing.T) {
+	position, _ := ParseFEN(`2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9`)
+	expect.Eq(t, position.Perft(4), int64(667366))
+}
+
+func TestSearch620(t *testing.T) {
+	position, _ := ParseFEN(`b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9`)
+	expect.Eq(t, position.Perft(4), int64(273318))
+}
+
+func TestSearch630(t *testing.T) {
+	position, _ := ParseFEN(`qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w hf - 0 9`)
+	expect.Eq(t, position.Perft(4), int64(382958))
+}
+
+func TestSearch640(t *testing.T) {
+	position, _ := ParseFEN(`1nbbnrkr/p1p1ppp1/3p4/1p3P1p/3Pq2P/8/PPP1P1P1/QNBBNRKR w HFhf - 0 9`)
+	expect.Eq(t, position.Perft(4), int64(1171749))
+}
+
+// Standard chess castles after Chess960 game.
+func TestSearch650(t *testing.T) {
+	ParseFEN(`bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9`)
+	position, _ := ParseFEN(`r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1`)
+	expect.Eq(t, position.Perft(3), int64(97862))
+}
+
+// Pruning near the leaves.
+func TestSearch700(t *testing.T) {
+	defer func(saved Engine) { engine = saved }(engine)
+
+	engine.pruneLate, engine.pruneSee, engine.pruneGood = true, true, true
+	p := NewGame(`Kg1,Qd1,Rf1,Nc3,a5`, `Kg8,Rf8,d5,e6`).start()
+	quiet, pawn, loser, capture := NewMove(p, F1, E1), NewMove(p, A5, A6), NewMove(p, C3, E4), NewMove(p, D1, D5)
+
+	// Late quiet moves.
+	expect.False(t, p.prunable(quiet, 1, 0, lateMovePruning[1]))
+	expect.True(t, p.prunable(quiet, 1, 0, lateMovePruning[1] + 1))
+	expect.False(t, p.prunable(pawn, 1, 0, lateMovePruning[1] + 1))
+	engine.pruneLate = false
+	expect.False(t, p.prunable(quiet, 1, 0, lateMovePruning[1] + 1))
+
+	// Quiet moves and captures that lose material.
+	expect.True(t, p.prunable(loser, 1, 0, 2))
+	expect.True(t, p.prunable(capture, 1, 0, 2))
+	engine.pruneSee = false
+	expect.False(t, p.prunable(loser, 1, 0, 2))
+	expect.False(t, p.prunable(capture, 1, 0, 2))
+
+	// Late quiet moves that have failed at greater depth.
+	game.history[quiet.piece()][quiet.to()] = -3 * 3
+	expect.True(t, p.prunable(quiet, 2, 0, 4))
+	expect.False(t, p.prunable(quiet, 2, 0, 3))
+	expect.False(t, p.prunable(quiet, 3, 0, 4))
+	engine.pruneGood = false
+	expect.False(t, p.prunable(quiet, 2, 0, 4))
+}
+
+// Pruning cuts down the number of nodes searched.
+func TestSearch710(t *testing.T) {
+	defer func(saved Engine) { engine = saved }(engine)
+
+	search := func() int {
+		p := NewGame(`r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4`).start()
+		game.getReady()
+		for depth := 1; depth <= 6; depth++ {
+			p.search(-Checkmate, Checkmate, depth)
+		}
+		return game.nodes
+	}
+
+	engine.pruneLate, engine.pruneSee, engine.pruneGood = true, true, true
+	pruned := search()
+	engine.pruneLate, engine.pruneSee, engine.pruneGood = false, false, false
+	expect.True(t, search() > pruned)
+}
@@ -7,6 +7,8 @@ package donna
 func (p *Position) searchTree(alpha, beta, depth int) (score int) {
 	ply := ply()
 
+	engine.poll()
+
 	// Return if it's time to stop search.
 	if ply >= MaxPly || engine.clock.halt {
 		return p.Evaluate()
@@ -17,7 +19,7 @@ func (p *Position) searchTree(alpha, beta, depth int) (score int) {
 
 	// Insufficient material and repetition/perpetual check pruning.
 	if p.fifty() || p.insufficient() || p.repetition() {
-		return 0
+		return drawScore(p.color)
 	}
 
 	// Checkmate distance pruning.
@@ -49,19 +51,38 @@ func (p *Position) searchTree(alpha, beta, depth int) (score int) {
 		}
 	}
 
+	// Probe endgame tablebases right after captures and pawn moves.
+	if p.count50 == 0 && ply > 0 && p.tbProbable() {
+		if wdl, ok := p.probeWDL(); ok {
+			game.tbhits++
+			score, flags := tbScore(wdl, ply), uint8(cacheExact)
+			if wdl > tbCursedWin {
+				flags = cacheBeta
+			} else if wdl < tbBlessedLoss {
+				flags = cacheAlpha
+			} else {
+				score += drawScore(p.color) - DrawScore
+			}
+			if flags == cacheExact || (flags == cacheBeta && score >= beta) || (flags == cacheAlpha && score <= alpha) {
+				p.cache(Move(0), score, min(MaxDepth, depth + 6), ply, flags)
+				return score
+			}
+		}
+	}
+
 	if !inCheck {
 		if depth < 1 {
 			return p.searchQuiescence(alpha, beta, 0, inCheck)
 		}
 		if cached != nil {
 			if p.score == Unknown {
-				p.score = p.Evaluate()
+				p.score = p.cachedEvaluation()
 			}
 		} else {
 			if isNull {
 				p.score = rightToMove.midgame * 2 - tree[node-1].score
 			} else {
-				p.score = p.Evaluate()
+				p.score = p.cachedEvaluation()
 			}
 		}
 	}
@@ -87,8 +108,9 @@ func (p *Position) searchTree(alpha, beta, depth int) (score int) {
 		}
 
 		// Futility pruning is only applicable if we don't have winning score
-		// yet and there are pieces other than pawns.
-		if !isNull && depth < 14 && !isMate(beta) &&
+		// yet (checkmate or decisive advantage in known endgame) and there
+		// are pieces other than pawns.
+		if !isNull && depth < 14 && abs(beta) < WhiteWinning &&
 		   (p.outposts[p.color] & ^(p.outposts[king(p.color)] | p.outposts[pawn(p.color)])).any() {
 			// Largest conceivable positional gain.
 			if gain := p.score - 256 * depth; gain >= beta {
@@ -96,18 +118,35 @@ func (p *Position) searchTree(alpha, beta, depth int) (score int) {
 			}
 		}
 
-		// Null move pruning.
-		if !isNull && depth > 1 && p.outposts[p.color].count() > 5 {
+		// Null move pruning. The null move is not allowed for the side being
+		// verified until the verification search gets deep enough.
+		if !isNull && depth > 1 && p.outposts[p.color].count() > 5 && (ply >= game.nullPly || p.color != game.nullColor) {
+			reduction := nullMoveReduction(depth, p.score - beta)
 			position := p.makeNullMove()
 			game.nodes++
-			nullScore := -position.searchTree(-beta, -beta + 1, depth - 1 - 3)
+			nullScore := -position.searchTree(-beta, -beta + 1, depth - 1 - reduction)
 			position.undoNullMove()
 
 			if nullScore >= beta {
 				if isMate(nullScore) {
-					return beta
+					nullScore = beta
+				}
+
+				// Trust the null move at lower depths unless we're left with
+				// minor pieces and pawns; otherwise run the verification search
+				// without the null move to make sure we're not in zugzwang.
+				majors := p.outposts[rook(p.color)] | p.outposts[queen(p.color)]
+				if game.nullPly != 0 || (depth < 10 && majors.any()) {
+					return nullScore
+				}
+
+				game.nullPly, game.nullColor = ply + 3 * (depth - reduction) / 4, p.color
+				score := p.searchTree(beta - 1, beta, depth - reduction)
+				game.nullPly = 0
+
+				if score >= beta {
+					return nullScore
 				}
-				return nullScore
 			}
 		}
 	}
@@ -138,11 +177,23 @@ func (p *Position) searchTree(alpha, beta, depth int) (score int) {
 			continue
 		}
 
+		// Prune late quiet moves, moves that lose material, and quiet moves
+		// with bad history near the leaves without making them. Never prune
+		// the first move so that we always have something to return, and
+		// leave pawn endgames alone.
+		if !isPrincipal && !inCheck && moveCount > 0 && depth < len(lateMovePruning) && !isMate(alpha) &&
+		   (p.outposts[p.color] & ^(p.outposts[king(p.color)] | p.outposts[pawn(p.color)])).any() &&
+		   !p.givesCheck(move) && p.prunable(move, depth, ply, moveCount + 1) {
+			moveCount++
+			continue
+		}
+
 		position := p.makeMove(move)
 		moveCount++; game.nodes++
 
-		// Reduce search depth if we're not checking.
 		giveCheck := position.isInCheck(position.color)
+
+		// Reduce search depth if we're not checking.
 		newDepth := let(giveCheck && p.exchange(move) >= 0, depth, depth - 1)
 
 		// Start search with full window.
@@ -194,7 +245,7 @@ func (p *Position) searchTree(alpha, beta, depth int) (score int) {
 	}
 
 	if moveCount == 0 {
-		score = let(inCheck, matedIn(ply), 0)
+		score = let(inCheck, matedIn(ply), drawScore(p.color))
 	} else {
 		score = bestScore
 		if !inCheck {
@@ -212,3 +263,37 @@ func (p *Position) searchTree(alpha, beta, depth int) (score int) {
 
 	return score
 }
+
+// Returns true if the move could be skipped near the leaves. Each pruning
+// technique could be turned off separately to measure its effect.
+func (p *Position) prunable(move Move, depth, ply, moveCount int) bool {
+	if move.isQuiet() {
+		if move.isKiller(ply) || move.isPawnAdvance() {
+			return false
+		}
+
+		// Late move pruning: skip quiet moves once we've searched enough
+		// of them at given depth.
+		if engine.pruneLate && moveCount > lateMovePruning[depth] {
+			return true
+		}
+
+		// History pruning: once first few moves have been searched skip
+		// quiet moves that have failed at greater depth before.
+		if engine.pruneGood && depth < 4 && moveCount > 3 && game.good(move) < -depth * depth {
+			return true
+		}
+
+		// Exchange pruning: skip quiet moves that leave the piece en prise.
+		return engine.pruneSee && depth < 4 && p.exchange(move) < -onePawn * depth * depth / 2
+	}
+
+	// Exchange pruning: skip captures that lose material.
+	return engine.pruneSee && !move.isPromo() && p.exchange(move) < -onePawn * depth
+}
+
+// Adaptive null move reduction: the deeper we search and the bigger the margin
+// over beta the more we reduce.
+func nullMoveReduction(depth, margin int) int {
+	return 3 + depth / 6 + min(3, max(0, margin) / onePawn)
+}
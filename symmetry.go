@@ -0,0 +1,148 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`bufio`
+	`fmt`
+	`os`
+	`sort`
+	`strings`
+)
+
+// Returns FEN of the position with colors flipped: the board is mirrored
+// vertically, white pieces become black ones and vice versa, and the other
+// side gets to move.
+func (p *Position) flipped() string {
+	var flip Position
+
+	for square, piece := range p.pieces {
+		if !piece.nil() {
+			flip.pieces[square ^ 56] = piece ^ 1
+		}
+	}
+	if p.enpassant != 0 {
+		flip.enpassant = p.enpassant ^ 56
+	}
+	flip.castles = (p.castles & 0x03) << 2 | (p.castles & 0x0C) >> 2
+	flip.color, flip.count50 = p.color ^ 1, p.count50
+
+	return flip.fen()
+}
+
+// Returns FEN of the position mirrored horizontally, i.e. A file becomes H
+// file and so on. Castle rights can't be mirrored and get dropped.
+func (p *Position) mirrored() string {
+	var mirror Position
+
+	for square, piece := range p.pieces {
+		if !piece.nil() {
+			mirror.pieces[square ^ 7] = piece
+		}
+	}
+	if p.enpassant != 0 {
+		mirror.enpassant = p.enpassant ^ 7
+	}
+	mirror.color, mirror.count50 = p.color, p.count50
+
+	return mirror.fen()
+}
+
+// Evaluates the position along with its color-flipped and mirrored copies and
+// compares the scores term by term. Flipped position must have white and black
+// terms swapped and White's point of view scores negated, while mirrored one
+// must match the original. Returns the list of mismatched metrics, if any.
+func symmetry(fen string) (diff []string) {
+	game.initial = fen
+	p := game.start()
+	if p == nil {
+		return []string{ fmt.Sprintf("Invalid position: %s", fen) }
+	}
+
+	// Castle rights are irrelevant for evaluation but they prevent the
+	// position from being mirrored, so get rid of them altogether.
+	p.castles = 0
+	flipped, mirrored := p.flipped(), p.mirrored()
+	score, metrics := p.EvaluateWithTrace()
+
+	for _, copy := range []struct{ name, fen string; flip bool }{ { `Flipped`, flipped, true }, { `Mirrored`, mirrored, false } } {
+		game.initial = copy.fen
+		other, otherMetrics := game.start().EvaluateWithTrace()
+
+		if other != score {
+			diff = append(diff, fmt.Sprintf("%s %s: Score %d != %d", copy.name, copy.fen, other, score))
+		}
+		for _, tag := range metricTags(metrics, otherMetrics) {
+			expected, ok := metrics[tag]
+			if ok && copy.flip {
+				expected = flipMetric(expected)
+			}
+			if actual := otherMetrics[tag]; actual != expected {
+				diff = append(diff, fmt.Sprintf("%s %s: %s %v != %v", copy.name, copy.fen, tag, actual, expected))
+			}
+		}
+	}
+
+	return
+}
+
+// Checks evaluation symmetry of positions from EPD file, printing all the
+// mismatches found. Returns the number of asymmetric positions.
+func symmetryCheck(fileName string) (count int, err error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			return count, fmt.Errorf("invalid position '%s'", line)
+		}
+
+		fen := strings.Join(fields[0:4], ` `) + ` 0 1`
+		if diff := symmetry(fen); len(diff) > 0 {
+			fmt.Printf("%s\n  %s\n", fen, strings.Join(diff, "\n  "))
+			count++
+		}
+	}
+
+	return count, scanner.Err()
+}
+
+// Returns sorted list of metric tags found in either of the metrics.
+func metricTags(metrics, other Metrics) (tags []string) {
+	for tag := range metrics {
+		tags = append(tags, tag)
+	}
+	for tag := range other {
+		if _, ok := metrics[tag]; !ok {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+
+	return
+}
+
+// Returns the metric as seen from the other side: white and black totals get
+// swapped and White's point of view scores get negated.
+func flipMetric(metric interface{}) interface{} {
+	switch metric.(type) {
+	case Total:
+		return Total{ metric.(Total).black, metric.(Total).white }
+	case Score:
+		return Score{}.minus(metric.(Score))
+	}
+
+	return metric
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `strings`; `testing`)
+
+// Positions covering pieces, threats, king safety, and pawns.
+var symmetrySuite = []string{
+	`rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1`,
+	`r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3`,
+	`r1bq1rk1/pp2bppp/2n1pn2/3p4/2PP4/2N1PN2/PP3PPP/R2QKB1R w KQ - 0 8`,
+	`r2q1rk1/1b2bppp/p2ppn2/1p6/3NP3/1BN1B3/PPP2PPP/R2Q1RK1 b - - 0 11`,
+	`2kr3r/ppp2ppp/2n1bn2/2b1p3/4P3/2NB1N2/PPP2PPP/R1B2RK1 w - - 4 10`,
+	`r4rk1/pp1nqppp/2p1pn2/3p4/1bPP4/2NBPN2/PPQ2PPP/R3K2R w KQ - 1 10`,
+	`6k1/5ppp/8/8/3r4/8/5PPP/3R2K1 w - - 0 1`,
+	`8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1`,
+	`r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1`,
+	`4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1`,
+	`2r3k1/pp3ppp/4p3/3nP3/3P4/P4N2/1q3PPP/3QR1K1 w - - 0 24`,
+	`8/8/4k3/8/2p5/8/B2P2K1/8 b - - 0 1`,
+	`r1b2rk1/2q1b1pp/p2ppn2/1p6/3QP3/1BN1B3/PPP3PP/R4RK1 w - - 0 1`,
+	`8/5pk1/6p1/4P3/1p1b4/1P3PP1/4K3/2B5 b - - 0 40`,
+}
+
+// Flipped and mirrored positions.
+func TestSymmetry000(t *testing.T) {
+	p := NewGame(`r3k2r/pp1n1ppp/8/2pP4/8/8/PPP2PPP/R3K1NR w KQq c6 0 1`).start()
+	expect.Eq(t, p.flipped(), `r3k1nr/ppp2ppp/8/8/2Pp4/8/PP1N1PPP/R3K2R b Qkq c3 0 1`)
+	expect.Eq(t, p.mirrored(), `r2k3r/ppp1n1pp/8/4Pp2/8/8/PPP2PPP/RN1K3R w - f6 0 1`)
+}
+
+// Evaluation is symmetric term by term.
+func TestSymmetry010(t *testing.T) {
+	for _, fen := range symmetrySuite {
+		expect.Eq(t, strings.Join(symmetry(fen), "\n"), ``)
+	}
+}
+
+// Mismatches get reported.
+func TestSymmetry020(t *testing.T) {
+	defer func(saved [14][64]Score) { pst = saved }(pst)
+
+	expect.Contain(t, symmetry(`invalid`)[0], `Invalid position`)
+
+	pst[Rook][D1].midgame += 10 // No matching change for black rook on D8.
+	diff := symmetry(`6k1/5ppp/8/8/3r4/8/5PPP/3R2K1 w - - 0 1`)
+	expect.Eq(t, len(diff), 6)
+	expect.Contain(t, diff[0], `Flipped 3r2k1/5ppp/8/3R4/8/8/5PPP/6K1 b - - 0 1: Score`)
+	expect.Contain(t, diff[1], `: Final`)
+	expect.Contain(t, diff[2], `: PST`)
+	expect.Contain(t, diff[3], `Mirrored 1k6/ppp5/8/8/4r3/8/PPP5/1K2R3 w - - 0 1: Score`)
+}
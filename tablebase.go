@@ -0,0 +1,984 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`encoding/binary`
+	`io/ioutil`
+	`os`
+	`path/filepath`
+	`sort`
+	`strings`
+)
+
+// Read-only Syzygy tablebases. Win/draw/loss (.rtbw) tables are probed during
+// search, and distance to zeroing move (.rtbz) tables are probed at the root.
+// The format and the indexing scheme follow the original probing code by
+// Ronald de Man.
+const (
+	tbPieces = 7 // Maximum number of pieces, including kings.
+
+	tbLoss = -2 // WDL scores from the side to move point of view.
+	tbBlessedLoss = -1
+	tbDraw = 0
+	tbCursedWin = 1
+	tbWin = 2
+
+	tbFail = iota // Probe results.
+	tbOk
+	tbChangeSide // DTZ table stores the other side to move.
+	tbZeroing    // Best move zeroes the fifty moves counter.
+
+	tbSTM = 1 // Table flags.
+	tbMapped = 2
+	tbWinPlies = 4
+	tbLossPlies = 8
+	tbWide = 16
+	tbSingleValue = 128
+
+	// Won tablebase position scores less than any checkmate but more than
+	// any evaluation.
+	tbWinScore = Checkmate - 2 * MaxPly
+)
+
+// Indexing and decompression data for one side to move and leading pawn file.
+type SyzygyPairs struct {
+	flags       uint8
+	minSymLen   int
+	maxSymLen   int
+	blockSize   int
+	span        uint64
+	numBlocks   int
+	lowestSym   int             // Offset of the lowest symbol of given length.
+	btree       int             // Offset of the pairs that expand the symbols.
+	sparseIndex int             // Offset of partial indices into block lengths.
+	sparseSize  int
+	blockLength int             // Offset of the number of values in each block.
+	blockSize16 int
+	data        int             // Offset of Huffman compressed data.
+	base64      []uint64        // Lowest symbol of given length padded to 64 bits.
+	symlen      []uint8         // Number of values (minus one) in each symbol.
+	pieces      [tbPieces]int   // Pieces in the order they're encoded.
+	groupIdx    [tbPieces+1]uint64
+	groupLen    [tbPieces+1]int
+	mapIdx      [4]int          // DTZ value maps for win, loss, cursed win and blessed loss.
+}
+
+// Memory image of .rtbw or .rtbz file, loaded on first access.
+type SyzygyFile struct {
+	name        string
+	dtz         bool
+	ready       bool
+	broken      bool
+	bytes       []byte
+	dtzMap      int
+	items       [2][4]SyzygyPairs // [side to move][leading pawn file]
+}
+
+// Pair of win/draw/loss and distance to zero tables for the given material.
+type Syzygy struct {
+	key         uint64          // Material key with stronger side being white...
+	key2        uint64          // ...and being black.
+	pieceCount  int
+	hasPawns    bool
+	hasUnique   bool            // At least one piece other than king is unique.
+	pawnCount   [2]int          // Pawns of the leading side and the other side.
+	wdl         SyzygyFile
+	dtz         SyzygyFile
+}
+
+type Tablebase struct {
+	path        string
+	pieces      int             // Maximum number of pieces we have tables for.
+	tables      map[uint64]*Syzygy
+}
+
+// Use single statically allocated variable.
+var tablebase Tablebase
+
+// Tablebase probes could go a few plies deeper than the search itself, so
+// they have their own move generators.
+var tbMoveList [2 * tbPieces + 2]MoveGen
+
+// Scans given directories for available tables. Multiple directories should
+// be separated by ":" (";" on Windows). Empty path disables tablebases.
+func NewTablebase(path string) *Tablebase {
+	tablebase = Tablebase{ path: path, tables: map[uint64]*Syzygy{} }
+
+	for _, dir := range filepath.SplitList(path) {
+		files, err := filepath.Glob(filepath.Join(dir, `*.rtbw`))
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			code := strings.TrimSuffix(filepath.Base(file), `.rtbw`)
+			if entry := newSyzygy(code, file); entry != nil {
+				tablebase.tables[entry.key] = entry
+				tablebase.tables[entry.key2] = entry
+				tablebase.pieces = max(tablebase.pieces, entry.pieceCount)
+			}
+		}
+	}
+
+	return &tablebase
+}
+
+// Creates tablebase entry for the given material code, ex. "KRPvKR".
+func newSyzygy(code, file string) *Syzygy {
+	sides := strings.Split(code, `v`)
+	if len(sides) != 2 || len(code) > tbPieces + 1 {
+		return nil
+	}
+
+	var count [14]int
+	for color, side := range sides {
+		if !strings.HasPrefix(side, `K`) {
+			return nil
+		}
+		for _, char := range side {
+			index := strings.IndexRune(`PNBRQK`, char)
+			if index < 0 {
+				return nil
+			}
+			count[Pawn + 2 * index + color]++
+		}
+	}
+
+	entry := &Syzygy{ pieceCount: len(code) - 1 }
+	entry.key, entry.key2 = tbMaterial(count, 0), tbMaterial(count, 1)
+	entry.hasPawns = count[Pawn] + count[BlackPawn] > 0
+	for piece := Pawn; piece < King; piece++ {
+		if count[piece] == 1 {
+			entry.hasUnique = true
+		}
+	}
+
+	// Leading side is the one with fewer pawns (if any) since it compresses
+	// better.
+	if count[BlackPawn] == 0 || (count[Pawn] > 0 && count[BlackPawn] >= count[Pawn]) {
+		entry.pawnCount = [2]int{ count[Pawn], count[BlackPawn] }
+	} else {
+		entry.pawnCount = [2]int{ count[BlackPawn], count[Pawn] }
+	}
+
+	entry.wdl.name = file
+	if dtz := strings.TrimSuffix(file, `.rtbw`) + `.rtbz`; exists(dtz) {
+		entry.dtz.name, entry.dtz.dtz = dtz, true
+	} else {
+		entry.dtz.broken = true
+	}
+
+	return entry
+}
+
+// Packs piece counts into material key, optionally flipping the colors.
+func tbMaterial(count [14]int, flip int) (key uint64) {
+	for piece := Pawn; piece < King; piece++ {
+		key |= uint64(count[piece ^ flip]) << (4 * uint(piece - Pawn))
+	}
+	return
+}
+
+func (p *Position) tbMaterial() uint64 {
+	var count [14]int
+	for piece := Pawn; piece < King; piece++ {
+		count[piece] = p.outposts[piece].count()
+	}
+	return tbMaterial(count, 0)
+}
+
+// Returns true if the position has few enough pieces to be probed.
+func (p *Position) tbProbable() bool {
+	return tablebase.pieces > 0 && p.castles == 0 && p.board.count() <= tablebase.pieces
+}
+
+// Syzygy piece codes are 1..6 for white pawn through king, and 9..14 for
+// black pieces.
+func tbPiece(piece Piece) int {
+	return piece.id() | int(piece.color()) << 3
+}
+
+// Returns move generator for tablebase probe at given recursion level.
+func tbMoves(p *Position, level int) *MoveGen {
+	gen := &tbMoveList[level]
+	gen.p, gen.ply = p, MaxPly
+	gen.head, gen.tail = 0, 0
+	gen.pins = p.pins(p.king[p.color])
+
+	return gen.generateAllMoves()
+}
+
+// Returns win/draw/loss score of the position from the side to move point of
+// view. Second return value is false if the table is not available.
+func (p *Position) probeWDL() (int, bool) {
+	wdl, result := p.tbSearch(false, 0)
+	return wdl, result != tbFail
+}
+
+// Returns distance to zeroing move in plies, positive if the side to move wins
+// and negative if it loses. Second return value is false if the table is not
+// available.
+func (p *Position) probeDTZ() (int, bool) {
+	return p.tbDistance(0)
+}
+
+// Tables don't store winning captures (and pawn moves for DTZ) but use them
+// as "don't care" values to improve compression; they also don't know about
+// en-passant. So we check captures first and then probe the table itself.
+func (p *Position) tbSearch(zeroing bool, level int) (int, int) {
+	if level >= len(tbMoveList) {
+		return tbDraw, tbFail
+	}
+
+	bestValue, moveCount, totalCount := tbLoss, 0, 0
+	gen := tbMoves(p, level)
+	for move := gen.NextMove(); !move.nil(); move = gen.NextMove() {
+		if !move.isValid(p, gen.pins) {
+			continue
+		}
+		totalCount++
+		if move.capture() == 0 && (!zeroing || !move.piece().isPawn()) {
+			continue
+		}
+		moveCount++
+
+		position := p.makeMove(move)
+		value, result := position.tbSearch(false, level + 1)
+		position.undoLastMove()
+
+		if result == tbFail {
+			return tbDraw, tbFail
+		}
+		if -value > bestValue {
+			bestValue = -value
+			if bestValue >= tbWin {
+				return bestValue, tbZeroing
+			}
+		}
+	}
+
+	// If all legal moves are captures the table value could be wrong, so
+	// go with what we've got.
+	value, noMoreMoves := bestValue, moveCount > 0 && moveCount == totalCount
+	if !noMoreMoves {
+		var result int
+		if value, result = p.tbProbe(false, tbDraw); result == tbFail {
+			return tbDraw, tbFail
+		}
+	}
+
+	if bestValue >= value {
+		if bestValue > tbDraw || noMoreMoves {
+			return bestValue, tbZeroing
+		}
+		return bestValue, tbOk
+	}
+
+	return value, tbOk
+}
+
+func (p *Position) tbDistance(level int) (int, bool) {
+	wdl, result := p.tbSearch(true, level)
+	if result == tbFail || wdl == tbDraw {
+		return 0, result != tbFail
+	}
+
+	// The table has "don't care" value, or wrong one if the best move is
+	// en-passant capture.
+	if result == tbZeroing {
+		return tbZeroingDistance(wdl), true
+	}
+
+	dtz, result := p.tbProbe(true, wdl)
+	if result == tbFail {
+		return 0, false
+	}
+	if result != tbChangeSide {
+		if wdl == tbCursedWin || wdl == tbBlessedLoss {
+			dtz += 100
+		}
+		return let(wdl > 0, dtz, -dtz), true
+	}
+
+	// The table stores the other side to move so we do 1-ply search to
+	// find the best move.
+	best := 0xFFFF
+	gen := tbMoves(p, level)
+	for move := gen.NextMove(); !move.nil(); move = gen.NextMove() {
+		if !move.isValid(p, gen.pins) {
+			continue
+		}
+		zeroing, ok := move.capture() != 0 || move.piece().isPawn(), true
+
+		// For zeroing moves we want the distance before making the move
+		// so we only need the score sign of the resulting position.
+		position := p.makeMove(move)
+		if zeroing {
+			value, result := position.tbSearch(false, level + 1)
+			dtz, ok = -tbZeroingDistance(value), result != tbFail
+		} else {
+			dtz, ok = position.tbDistance(level + 1)
+			dtz = -dtz
+		}
+		if dtz == 1 && position.isInCheck(position.color) && !position.tbAnyMove(level + 1) {
+			best = 1 // Checkmate.
+		}
+		position.undoLastMove()
+
+		if !ok {
+			return 0, false
+		}
+		if !zeroing {
+			dtz += sign(dtz)
+		}
+		if dtz < best && sign(dtz) == sign(wdl) {
+			best = dtz
+		}
+	}
+
+	return let(best == 0xFFFF, -1, best), true
+}
+
+// Returns true if the side to move has at least one legal move.
+func (p *Position) tbAnyMove(level int) bool {
+	return level < len(tbMoveList) && tbMoves(p, level).anyValid()
+}
+
+// Distance to zero for the position right before the zeroing move.
+func tbZeroingDistance(wdl int) int {
+	switch wdl {
+	case tbWin:
+		return 1
+	case tbCursedWin:
+		return 101
+	case tbBlessedLoss:
+		return -101
+	case tbLoss:
+		return -1
+	}
+	return 0
+}
+
+// Locates the table, loads it if necessary, and reads the value stored for
+// the position.
+func (p *Position) tbProbe(dtz bool, wdl int) (int, int) {
+	if p.board.count() == 2 {
+		return tbDraw, tbOk // Bare kings.
+	}
+
+	entry := tablebase.tables[p.tbMaterial()]
+	if entry == nil {
+		return 0, tbFail
+	}
+
+	file := &entry.wdl
+	if dtz {
+		file = &entry.dtz
+	}
+	if !file.ready && !file.broken {
+		file.broken = !entry.load(file)
+		file.ready = !file.broken
+	}
+	if file.broken {
+		return 0, tbFail
+	}
+
+	return entry.probe(p, file, wdl)
+}
+
+// Reads the table file and sets up indexing and decompression data. Returns
+// false if the file is missing or corrupted.
+func (entry *Syzygy) load(file *SyzygyFile) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+
+	bytes, err := ioutil.ReadFile(file.name)
+	if err != nil || len(bytes) < 8 {
+		return false
+	}
+
+	magic := uint32(0x5D23E871)
+	if file.dtz {
+		magic = 0xA50C66D7
+	}
+	if binary.LittleEndian.Uint32(bytes) != magic {
+		return false
+	}
+	if (bytes[4] & 2 != 0) != entry.hasPawns || (bytes[4] & 1 != 0) != (entry.key != entry.key2) {
+		return false
+	}
+
+	file.bytes = bytes
+	entry.setup(file)
+
+	return true
+}
+
+func (entry *Syzygy) setup(file *SyzygyFile) {
+	data, offset := file.bytes, 5
+	sides, files := 1, 1
+	if !file.dtz && entry.key != entry.key2 {
+		sides = 2
+	}
+	if entry.hasPawns {
+		files = 4
+	}
+
+	// Piece order and group order for each file and side to move.
+	both := entry.hasPawns && entry.pawnCount[1] > 0
+	for f := 0; f < files; f++ {
+		order := [2][2]int{ { int(data[offset] & 0xF), 0xF }, { int(data[offset] >> 4), 0xF } }
+		if both {
+			order[0][1], order[1][1] = int(data[offset + 1] & 0xF), int(data[offset + 1] >> 4)
+			offset++
+		}
+		offset++
+
+		for k := 0; k < entry.pieceCount; k, offset = k + 1, offset + 1 {
+			file.items[0][f].pieces[k] = int(data[offset] & 0xF)
+			file.items[1][f].pieces[k] = int(data[offset] >> 4)
+		}
+		for side := 0; side < sides; side++ {
+			entry.setGroups(&file.items[side][f], order[side], f)
+		}
+	}
+	offset += offset & 1
+
+	for f := 0; f < files; f++ {
+		for side := 0; side < sides; side++ {
+			offset = file.items[side][f].setSizes(data, offset)
+		}
+	}
+
+	// DTZ value maps.
+	if file.dtz {
+		file.dtzMap = offset
+		for f := 0; f < files; f++ {
+			pairs := &file.items[0][f]
+			if pairs.flags & tbMapped == 0 {
+				continue
+			}
+			if pairs.flags & tbWide != 0 {
+				offset += offset & 1
+				for i := 0; i < 4; i++ {
+					pairs.mapIdx[i] = (offset - file.dtzMap) / 2 + 1
+					offset += 2 * int(binary.LittleEndian.Uint16(data[offset:])) + 2
+				}
+			} else {
+				for i := 0; i < 4; i++ {
+					pairs.mapIdx[i] = offset - file.dtzMap + 1
+					offset += int(data[offset]) + 1
+				}
+			}
+		}
+		offset += offset & 1
+	}
+
+	for f := 0; f < files; f++ {
+		for side := 0; side < sides; side++ {
+			file.items[side][f].sparseIndex = offset
+			offset += file.items[side][f].sparseSize * 6
+		}
+	}
+	for f := 0; f < files; f++ {
+		for side := 0; side < sides; side++ {
+			file.items[side][f].blockLength = offset
+			offset += file.items[side][f].blockSize16 * 2
+		}
+	}
+	for f := 0; f < files; f++ {
+		for side := 0; side < sides; side++ {
+			offset = (offset + 0x3F) &^ 0x3F // 64 byte alignment.
+			file.items[side][f].data = offset
+			offset += file.items[side][f].numBlocks * file.items[side][f].blockSize
+		}
+	}
+}
+
+// Groups together the pieces that are encoded together: pieces of the same
+// kind and color, except for the leading group that is either pawns, three
+// unique pieces, or two kings. For example, KRvKN -> KRK + N.
+func (entry *Syzygy) setGroups(pairs *SyzygyPairs, order [2]int, file int) {
+	n, first := 0, let(entry.hasPawns, 0, let(entry.hasUnique, 3, 2))
+	pairs.groupLen[n] = 1
+	for i := 1; i < entry.pieceCount; i++ {
+		if first--; first > 0 || pairs.pieces[i] == pairs.pieces[i-1] {
+			pairs.groupLen[n]++
+		} else {
+			n++
+			pairs.groupLen[n] = 1
+		}
+	}
+	n++
+	pairs.groupLen[n] = 0
+
+	// Groups are encoded in the order stored in the table: the leading group
+	// is at order[0] position, and remaining pawns (if both sides have them)
+	// are at order[1].
+	both := entry.hasPawns && entry.pawnCount[1] > 0
+	next, free, index := 1, 64 - pairs.groupLen[0], uint64(1)
+	if both {
+		next, free = 2, free - pairs.groupLen[1]
+	}
+	for k := 0; next < n || k == order[0] || k == order[1]; k++ {
+		if k == order[0] {
+			pairs.groupIdx[0] = index
+			if entry.hasPawns {
+				index *= tbLeadPawnsSize[pairs.groupLen[0]][file]
+			} else if entry.hasUnique {
+				index *= 31332
+			} else {
+				index *= 462
+			}
+		} else if k == order[1] {
+			pairs.groupIdx[1] = index
+			index *= tbBinomial[pairs.groupLen[1]][48 - pairs.groupLen[0]]
+		} else {
+			pairs.groupIdx[next] = index
+			index *= tbBinomial[pairs.groupLen[next]][free]
+			free -= pairs.groupLen[next]
+			next++
+		}
+	}
+	pairs.groupIdx[n] = index
+}
+
+// Reads block sizes and canonical Huffman code parameters. Returns the offset
+// of the next record.
+func (pairs *SyzygyPairs) setSizes(data []byte, offset int) int {
+	pairs.flags = data[offset]
+	if pairs.flags & tbSingleValue != 0 {
+		pairs.minSymLen = int(data[offset + 1]) // The value itself.
+		return offset + 2
+	}
+
+	size := uint64(0)
+	for i := 0; i <= tbPieces; i++ {
+		if pairs.groupLen[i] == 0 {
+			size = pairs.groupIdx[i]
+			break
+		}
+	}
+
+	pairs.blockSize = 1 << data[offset + 1]
+	pairs.span = 1 << data[offset + 2]
+	pairs.sparseSize = int((size + pairs.span - 1) / pairs.span)
+	pairs.numBlocks = int(binary.LittleEndian.Uint32(data[offset + 4:]))
+	pairs.blockSize16 = pairs.numBlocks + int(data[offset + 3]) // Padded.
+	pairs.maxSymLen = int(data[offset + 8])
+	pairs.minSymLen = int(data[offset + 9])
+	pairs.lowestSym = offset + 10
+
+	// Longer symbols have lower numeric values so base64[i] >= base64[i+1].
+	pairs.base64 = make([]uint64, pairs.maxSymLen - pairs.minSymLen + 1)
+	for i := len(pairs.base64) - 2; i >= 0; i-- {
+		pairs.base64[i] = (pairs.base64[i+1] + uint64(pairs.lowest(data, i)) - uint64(pairs.lowest(data, i+1))) / 2
+	}
+	for i := range pairs.base64 {
+		pairs.base64[i] <<= uint(64 - i - pairs.minSymLen)
+	}
+
+	offset = pairs.lowestSym + 2 * len(pairs.base64)
+	symbols := int(binary.LittleEndian.Uint16(data[offset:]))
+	pairs.btree = offset + 2
+	pairs.symlen = make([]uint8, symbols)
+
+	visited := make([]bool, symbols)
+	for sym := 0; sym < symbols; sym++ {
+		if !visited[sym] {
+			pairs.symlen[sym] = pairs.setSymlen(data, sym, visited)
+		}
+	}
+
+	return pairs.btree + 3 * symbols + symbols & 1
+}
+
+// Each symbol stands for a pair of symbols that stand for pairs of symbols,
+// and so on until we reach the values. Returns the number of values (minus
+// one) the symbol expands to.
+func (pairs *SyzygyPairs) setSymlen(data []byte, sym int, visited []bool) uint8 {
+	visited[sym] = true
+	left, right := pairs.children(data, sym)
+	if right == 0xFFF {
+		return 0
+	}
+
+	if !visited[left] {
+		pairs.symlen[left] = pairs.setSymlen(data, left, visited)
+	}
+	if !visited[right] {
+		pairs.symlen[right] = pairs.setSymlen(data, right, visited)
+	}
+
+	return pairs.symlen[left] + pairs.symlen[right] + 1
+}
+
+// Returns lowest symbol of the given length (minus the minimum length).
+func (pairs *SyzygyPairs) lowest(data []byte, length int) int {
+	return int(binary.LittleEndian.Uint16(data[pairs.lowestSym + 2 * length:]))
+}
+
+// Returns left and right symbols the symbol expands to. Left symbol of the
+// leaf stores the value.
+func (pairs *SyzygyPairs) children(data []byte, sym int) (int, int) {
+	lr := data[pairs.btree + 3 * sym:]
+	return int(lr[1] & 0xF) << 8 | int(lr[0]), int(lr[2]) << 4 | int(lr[1] >> 4)
+}
+
+// Returns the value stored at the given index.
+func (pairs *SyzygyPairs) decompress(data []byte, index uint64) int {
+	if pairs.flags & tbSingleValue != 0 {
+		return pairs.minSymLen
+	}
+
+	// Find the block that stores the index using the nearest sparse index
+	// entry that points somewhere in the middle of the span.
+	k := index / pairs.span
+	entry := data[pairs.sparseIndex + 6 * int(k):]
+	block := int(binary.LittleEndian.Uint32(entry))
+	offset := int(binary.LittleEndian.Uint16(entry[4:]))
+	offset += int(index % pairs.span) - int(pairs.span / 2)
+
+	length := func(block int) int {
+		return int(binary.LittleEndian.Uint16(data[pairs.blockLength + 2 * block:]))
+	}
+	for offset < 0 {
+		block--
+		offset += length(block) + 1
+	}
+	for offset > length(block) {
+		offset -= length(block) + 1
+		block++
+	}
+
+	// Read canonical Huffman symbols from the beginning of the block until
+	// we find the one that covers our offset.
+	ptr := pairs.data + block * pairs.blockSize
+	buffer, bits := binary.BigEndian.Uint64(data[ptr:]), 64
+	ptr += 8
+
+	sym := 0
+	for {
+		length := 0
+		for buffer < pairs.base64[length] {
+			length++
+		}
+		sym = int((buffer - pairs.base64[length]) >> uint(64 - length - pairs.minSymLen))
+		sym += pairs.lowest(data, length)
+
+		if offset < int(pairs.symlen[sym]) + 1 {
+			break
+		}
+		offset -= int(pairs.symlen[sym]) + 1
+		length += pairs.minSymLen
+		buffer <<= uint(length)
+		bits -= length
+		if bits <= 32 {
+			bits += 32
+			if ptr + 4 <= len(data) {
+				buffer |= uint64(binary.BigEndian.Uint32(data[ptr:])) << uint(64 - bits)
+			}
+			ptr += 4
+		}
+	}
+
+	// Expand the symbol into pairs until we reach the value.
+	for pairs.symlen[sym] != 0 {
+		left, right := pairs.children(data, sym)
+		if offset < int(pairs.symlen[left]) + 1 {
+			sym = left
+		} else {
+			offset -= int(pairs.symlen[left]) + 1
+			sym = right
+		}
+	}
+	value, _ := pairs.children(data, sym)
+
+	return value
+}
+
+// Computes the index of the position within the table and returns the value
+// stored there: WDL score or distance to zero.
+func (entry *Syzygy) probe(p *Position, file *SyzygyFile, wdl int) (int, int) {
+	var squares, pieces [tbPieces]int
+	var leadPawns Bitmask
+	var index uint64
+
+	// Tables are calculated with white being the stronger side, and only
+	// white to move is stored if both sides have the same material. Flip
+	// colors and squares as necessary.
+	flip := (entry.key == entry.key2 && p.color == Black) || p.tbMaterial() != entry.key
+	flipColor, flipSquares, stm := 0, 0, int(p.color)
+	if flip {
+		flipColor, flipSquares, stm = 8, 070, stm ^ 1
+	}
+
+	// Tables with pawns are split by the file of the leading pawn, which
+	// is the one closest to the edge and then the lowest rank.
+	size, leadCount, f := 0, 0, 0
+	if entry.hasPawns {
+		color := uint8((file.items[0][0].pieces[0] ^ flipColor) >> 3)
+		leadPawns = p.outposts[pawn(color)]
+		for pawns := leadPawns; pawns.any(); size++ {
+			squares[size] = pawns.pop() ^ flipSquares
+		}
+		leadCount = size
+		for i := 1; i < leadCount; i++ {
+			if tbMapPawns[squares[i]] > tbMapPawns[squares[0]] {
+				squares[0], squares[i] = squares[i], squares[0]
+			}
+		}
+		f = min(col(squares[0]), 7 - col(squares[0]))
+	}
+
+	// DTZ tables store one side to move only.
+	if file.dtz && file.items[0][f].flags & tbSTM != uint8(stm) && (entry.key != entry.key2 || entry.hasPawns) {
+		return 0, tbChangeSide
+	}
+
+	for board := p.board ^ leadPawns; board.any(); size++ {
+		square := board.pop()
+		squares[size] = square ^ flipSquares
+		pieces[size] = tbPiece(p.pieces[square]) ^ flipColor
+	}
+
+	side := stm
+	if file.dtz {
+		side = 0
+	}
+	pairs := &file.items[side][f]
+
+	// Arrange the pieces in the same order as the table does.
+	for i := leadCount; i < size - 1; i++ {
+		for j := i + 1; j < size; j++ {
+			if pairs.pieces[i] == pieces[j] {
+				pieces[i], pieces[j] = pieces[j], pieces[i]
+				squares[i], squares[j] = squares[j], squares[i]
+				break
+			}
+		}
+	}
+
+	// Mirror the board so that the leading piece ends up on files A-D.
+	if col(squares[0]) > 3 {
+		for i := 0; i < size; i++ {
+			squares[i] ^= 7
+		}
+	}
+
+	if entry.hasPawns {
+		index = tbLeadPawnIdx[leadCount][squares[0]]
+		sort.Slice(squares[1:leadCount], func(i, j int) bool {
+			return tbMapPawns[squares[i+1]] < tbMapPawns[squares[j+1]]
+		})
+		for i := 1; i < leadCount; i++ {
+			index += tbBinomial[i][tbMapPawns[squares[i]]]
+		}
+	} else {
+		// Without pawns also make sure the leading piece is on ranks 1-4,
+		// and then below A1-H8 diagonal.
+		if row(squares[0]) > 3 {
+			for i := 0; i < size; i++ {
+				squares[i] ^= 070
+			}
+		}
+		for i := 0; i < pairs.groupLen[0]; i++ {
+			if offDiagonal(squares[i]) == 0 {
+				continue
+			}
+			if offDiagonal(squares[i]) > 0 {
+				for j := i; j < size; j++ {
+					squares[j] = ((squares[j] >> 3) | (squares[j] << 3)) & 63
+				}
+			}
+			break
+		}
+		index = entry.encodeLeading(squares)
+	}
+
+	// Encode remaining groups. Squares taken by previous groups are skipped,
+	// and so are the first and last ranks for remaining pawns.
+	index *= pairs.groupIdx[0]
+	start, pawns := pairs.groupLen[0], entry.hasPawns && entry.pawnCount[1] > 0
+	for next := 1; pairs.groupLen[next] != 0; next++ {
+		group := squares[start : start + pairs.groupLen[next]]
+		sort.Ints(group)
+
+		n := uint64(0)
+		for i, square := range group {
+			adjust := 0
+			for _, taken := range squares[:start] {
+				if square > taken {
+					adjust++
+				}
+			}
+			n += tbBinomial[i+1][square - adjust - let(pawns, 8, 0)]
+		}
+		pawns = false
+		index += n * pairs.groupIdx[next]
+		start += pairs.groupLen[next]
+	}
+
+	value := pairs.decompress(file.bytes, index)
+	if !file.dtz {
+		return value - 2, tbOk
+	}
+
+	return file.distance(f, value, wdl), tbOk
+}
+
+// Encodes leading group of pieces without pawns: either three unique pieces
+// or just the kings.
+func (entry *Syzygy) encodeLeading(squares [tbPieces]int) uint64 {
+	if !entry.hasUnique {
+		return uint64(tbMapKK[tbMapA1D1D4[squares[0]]][squares[1]])
+	}
+
+	adjust1 := let(squares[1] > squares[0], 1, 0)
+	adjust2 := let(squares[2] > squares[0], 1, 0) + let(squares[2] > squares[1], 1, 0)
+
+	switch {
+	case offDiagonal(squares[0]) != 0:
+		return uint64((tbMapA1D1D4[squares[0]] * 63 + squares[1] - adjust1) * 62 + squares[2] - adjust2)
+	case offDiagonal(squares[1]) != 0:
+		return uint64((6 * 63 + row(squares[0]) * 28 + tbMapB1H1H7[squares[1]]) * 62 + squares[2] - adjust2)
+	case offDiagonal(squares[2]) != 0:
+		return uint64(6 * 63 * 62 + 4 * 28 * 62 + row(squares[0]) * 7 * 28 + (row(squares[1]) - adjust1) * 28 + tbMapB1H1H7[squares[2]])
+	}
+	return uint64(6 * 63 * 62 + 4 * 28 * 62 + 4 * 7 * 28 + row(squares[0]) * 7 * 6 + (row(squares[1]) - adjust1) * 6 + row(squares[2]) - adjust2)
+}
+
+// Converts stored DTZ value to plies.
+func (file *SyzygyFile) distance(f, value, wdl int) int {
+	pairs := &file.items[0][f]
+	if pairs.flags & tbMapped != 0 {
+		index := pairs.mapIdx[[5]int{ 1, 3, 0, 2, 0 }[wdl + 2]] + value
+		if pairs.flags & tbWide != 0 {
+			value = int(binary.LittleEndian.Uint16(file.bytes[file.dtzMap + 2 * index:]))
+		} else {
+			value = int(file.bytes[file.dtzMap + index])
+		}
+	}
+
+	if (wdl == tbWin && pairs.flags & tbWinPlies == 0) || (wdl == tbLoss && pairs.flags & tbLossPlies == 0) ||
+	   wdl == tbCursedWin || wdl == tbBlessedLoss {
+		value *= 2
+	}
+
+	return value + 1
+}
+
+// Converts WDL score to search score. Cursed wins and blessed losses are
+// draws under fifty moves rule.
+func tbScore(wdl, ply int) int {
+	switch {
+	case wdl > tbCursedWin:
+		return tbWinScore - ply
+	case wdl < tbBlessedLoss:
+		return ply - tbWinScore
+	}
+	return DrawScore + wdl
+}
+
+// Keeps root moves that preserve tablebase result: the ones with the shortest
+// distance to zero when winning, and the longest one when losing. Falls back
+// to WDL tables if DTZ ones are not available.
+func (gen *MoveGen) tablebaseMoves() *MoveGen {
+	p := gen.p
+	if !p.tbProbable() {
+		return gen
+	}
+
+	var ranks [128]int
+	if !gen.rankByDistance(ranks[:]) && !gen.rankByScore(ranks[:]) {
+		return gen
+	}
+	game.tbhits++
+
+	best := ranks[0]
+	for i := 1; i < gen.tail; i++ {
+		best = max(best, ranks[i])
+	}
+	tail := 0
+	for i := 0; i < gen.tail; i++ {
+		if ranks[i] == best {
+			gen.list[tail] = gen.list[i]
+			tail++
+		}
+	}
+	gen.head, gen.tail = 0, tail
+
+	return gen
+}
+
+func (gen *MoveGen) rankByDistance(ranks []int) bool {
+	p := gen.p
+	for i := 0; i < gen.tail; i++ {
+		dtz, ok, position := 0, true, p.makeMove(gen.list[i].move)
+		if position.count50 == 0 {
+			var wdl int
+			wdl, ok = position.probeWDL()
+			dtz = tbZeroingDistance(-wdl)
+		} else if dtz, ok = position.probeDTZ(); ok {
+			dtz = -dtz
+			dtz += sign(dtz)
+		}
+		if dtz == 2 && position.isInCheck(position.color) && !position.tbAnyMove(0) {
+			dtz = 1 // Checkmate.
+		}
+		position.undoLastMove()
+
+		if !ok {
+			return false
+		}
+
+		// Prefer the shortest win and the longest loss, and lean toward
+		// fifty moves rule when losing.
+		switch {
+		case dtz > 0 && dtz + int(p.count50) <= 99:
+			ranks[i] = 1000 - dtz
+		case dtz > 0:
+			ranks[i] = max(1, 500 - dtz)
+		case dtz < 0 && -dtz + int(p.count50) <= 99:
+			ranks[i] = -1000 - dtz
+		case dtz < 0:
+			ranks[i] = -1
+		default:
+			ranks[i] = 0
+		}
+	}
+
+	return true
+}
+
+func (gen *MoveGen) rankByScore(ranks []int) bool {
+	p := gen.p
+	for i := 0; i < gen.tail; i++ {
+		position := p.makeMove(gen.list[i].move)
+		wdl, ok := position.probeWDL()
+		position.undoLastMove()
+
+		if !ok {
+			return false
+		}
+		ranks[i] = -wdl
+	}
+
+	return true
+}
+
+// Returns -1, 0, or 1 depending on the sign of the number.
+func sign(n int) int {
+	return let(n > 0, 1, let(n < 0, -1, 0))
+}
+
+// Returns true if the file exists.
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
@@ -0,0 +1,365 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `encoding/binary`; `io/ioutil`; `os`; `path/filepath`; `testing`)
+
+// Writes single valued KQvK table: white to move wins, black to move loses.
+func tbSingleValued(t *testing.T) string {
+	dir, err := ioutil.TempDir(``, `syzygy`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte{
+		0x71, 0xE8, 0x23, 0x5D, // Magic.
+		0x01,                   // Split by side to move, no pawns.
+		0x00,                   // Group order.
+		0x66, 0x55, 0xEE,       // White king, white queen, black king.
+		0x00,                   // Word alignment.
+		0x80, 0x04,             // White to move: single value, win.
+		0x80, 0x00,             // Black to move: single value, loss.
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, `KQvK.rtbw`), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+// Writes KQvK table with compressed white to move values: one bit per value,
+// set for draws and clear for wins, four blocks of 8192 values each. Black to
+// move always loses.
+func tbCompressed(t *testing.T, draws ...int) string {
+	dir, err := ioutil.TempDir(``, `syzygy`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte{
+		0x71, 0xE8, 0x23, 0x5D, // Magic.
+		0x01,                   // Split by side to move, no pawns.
+		0x00,                   // Group order.
+		0x66, 0x55, 0xEE,       // White king, white queen, black king.
+		0x00,                   // Word alignment.
+		0x00,                   // White to move: compressed.
+		10, 13, 0,              // Block size 1024, span 8192, no padding.
+		4, 0, 0, 0,             // Four blocks.
+		1, 1,                   // Maximum and minimum symbol length.
+		0, 0,                   // Lowest symbol of length 1.
+		2, 0,                   // Two symbols...
+		4, 0xF0, 0xFF,          // ...win...
+		2, 0xF0, 0xFF,          // ...and draw.
+		0x80, 0x00,             // Black to move: single value, loss.
+	}
+
+	// Sparse index entries point to the middle of each span, followed by
+	// the number of values (minus one) in each block.
+	for k := 0; k < 4; k++ {
+		index := k * 8192 + 4096
+		entry := make([]byte, 6)
+		binary.LittleEndian.PutUint32(entry, uint32(index / 8192))
+		binary.LittleEndian.PutUint16(entry[4:], uint16(index % 8192))
+		data = append(data, entry...)
+	}
+	data = append(data, 0xFF, 0x1F, 0xFF, 0x1F, 0xFF, 0x1F, 0x63, 0x1A)
+
+	offset := (len(data) + 0x3F) &^ 0x3F
+	data = append(data, make([]byte, offset - len(data) + 4 * 1024)...)
+	for _, index := range draws {
+		bit := offset * 8 + (index / 8192) * 1024 * 8 + index % 8192
+		data[bit / 8] |= 0x80 >> uint(bit % 8)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, `KQvK.rtbw`), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+// Pairs of kings.
+func TestTablebase000(t *testing.T) {
+	codes := map[int]bool{}
+	for index := 0; index < 10; index++ {
+		for sq := A1; sq <= H8; sq++ {
+			if code := tbMapKK[index][sq]; code != 0 {
+				codes[code] = true
+			}
+		}
+	}
+	expect.Eq(t, len(codes), 461) // Plus zero.
+	expect.Eq(t, tbMapKK[0][D1], 0) // B1 and D1.
+	expect.Eq(t, tbMapKK[0][E1], 1) // B1 and E1.
+}
+
+// Binomials and pawns.
+func TestTablebase010(t *testing.T) {
+	expect.Eq(t, tbBinomial[2][62], uint64(62 * 61 / 2))
+	expect.Eq(t, tbBinomial[3][10], uint64(120))
+	expect.Eq(t, tbMapPawns[A2], 47)
+	expect.Eq(t, tbMapPawns[H2], 46)
+	expect.Eq(t, tbMapPawns[D7], 1)
+	expect.Eq(t, tbMapPawns[E7], 0)
+	expect.Eq(t, tbLeadPawnsSize[1][0], uint64(6))
+	expect.Eq(t, tbLeadPawnsSize[2][0], uint64(47 + 45 + 43 + 41 + 39 + 37))
+}
+
+// Material keys.
+func TestTablebase020(t *testing.T) {
+	entry := newSyzygy(`KRPvKR`, `KRPvKR.rtbw`)
+	expect.Eq(t, entry.pieceCount, 5)
+	expect.True(t, entry.hasPawns)
+	expect.True(t, entry.hasUnique)
+	expect.Eq(t, entry.pawnCount, [2]int{ 1, 0 })
+
+	p := NewGame(`Ka1,Rb1,c2`, `Kh8,Rg8`).start()
+	expect.Eq(t, p.tbMaterial(), entry.key)
+	p = NewGame(`Ka1,Rb1`, `Kh8,Rg8,c7`).start()
+	expect.Eq(t, p.tbMaterial(), entry.key2)
+
+	expect.Eq(t, newSyzygy(`KRvKRR`, `KRvKRR.rtbw`) == nil, false)
+	expect.True(t, newSyzygy(`KXvK`, `KXvK.rtbw`) == nil)
+	expect.True(t, newSyzygy(`KQK`, `KQK.rtbw`) == nil)
+}
+
+// Single valued table.
+func TestTablebase030(t *testing.T) {
+	dir := tbSingleValued(t)
+	defer os.RemoveAll(dir)
+	defer NewTablebase(``)
+
+	NewTablebase(dir)
+	expect.Eq(t, tablebase.pieces, 3)
+
+	wdl, ok := NewGame(`Ke1,Qd4`, `Ke8`).start().probeWDL()
+	expect.True(t, ok)
+	expect.Eq(t, wdl, tbWin)
+
+	wdl, ok = NewGame(`Ke1,Qd4`, `M,Ke8`).start().probeWDL()
+	expect.True(t, ok)
+	expect.Eq(t, wdl, tbLoss)
+
+	// Same with colors reversed.
+	wdl, ok = NewGame(`Ke1`, `M,Ke8,Qd4`).start().probeWDL()
+	expect.True(t, ok)
+	expect.Eq(t, wdl, tbWin)
+
+	// No table.
+	_, ok = NewGame(`Ke1,Rd4`, `Ke8`).start().probeWDL()
+	expect.False(t, ok)
+}
+
+// Winning capture beats the table.
+func TestTablebase040(t *testing.T) {
+	dir := tbSingleValued(t)
+	defer os.RemoveAll(dir)
+	defer NewTablebase(``)
+
+	NewTablebase(dir)
+	wdl, ok := NewGame(`Ke1,Qe7`, `M,Kd8`).start().probeWDL()
+	expect.True(t, ok)
+	expect.Eq(t, wdl, tbDraw) // Kd8xe7 leaves bare kings.
+}
+
+// Huffman decompression: two single value symbols, one bit each.
+func TestTablebase050(t *testing.T) {
+	expected := func(index int) int {
+		return let(index % 3 == 0, 4, 2)
+	}
+
+	data := []byte{
+		0x00,                   // Flags.
+		5, 6, 0,                // Block size 32, span 64, no padding.
+		2, 0, 0, 0,             // Two blocks.
+		1, 1,                   // Maximum and minimum symbol length.
+		0, 0,                   // Lowest symbol of length 1.
+		2, 0,                   // Two symbols...
+		4, 0xF0, 0xFF,          // ...win...
+		2, 0xF0, 0xFF,          // ...and draw.
+	}
+	pairs := SyzygyPairs{}
+	pairs.groupLen[0], pairs.groupIdx[1] = 1, 200
+	offset := pairs.setSizes(data, 0)
+	expect.Eq(t, offset, len(data))
+	expect.Eq(t, pairs.sparseSize, 4)
+	expect.Eq(t, pairs.symlen, []uint8{ 0, 0 })
+
+	// Sparse index entries point to the middle of each span, and both
+	// blocks store 100 values.
+	pairs.sparseIndex = len(data)
+	for k := 0; k < pairs.sparseSize; k++ {
+		index := k * 64 + 32
+		entry := make([]byte, 6)
+		binary.LittleEndian.PutUint32(entry, uint32(index / 100))
+		binary.LittleEndian.PutUint16(entry[4:], uint16(index % 100))
+		data = append(data, entry...)
+	}
+	pairs.blockLength = len(data)
+	data = append(data, 99, 0, 99, 0)
+
+	pairs.data = len(data)
+	data = append(data, make([]byte, 2 * 32 + 8)...)
+	for index := 0; index < 200; index++ {
+		if expected(index) == 2 {
+			bit := pairs.data * 8 + (index / 100) * 32 * 8 + index % 100
+			data[bit / 8] |= 0x80 >> uint(bit % 8)
+		}
+	}
+
+	for index := 0; index < 200; index++ {
+		expect.Eq(t, pairs.decompress(data, uint64(index)), expected(index))
+	}
+}
+
+// Root moves that preserve the win.
+func TestTablebase060(t *testing.T) {
+	dir := tbSingleValued(t)
+	defer os.RemoveAll(dir)
+	defer NewTablebase(``)
+
+	NewTablebase(dir)
+	p := NewGame(`Ke1,Qb2`, `Kc3`).start()
+	game.getReady()
+	gen := NewRootGen(p, 1).generateRootMoves()
+	expect.True(t, gen.size() > 10)
+	for move := gen.NextMove(); !move.nil(); move = gen.NextMove() {
+		expect.Eq(t, move.piece(), Piece(Queen))
+		expect.False(t, kingMoves[C3].on(move.to()) && !kingMoves[E1].on(move.to()))
+	}
+	expect.Eq(t, game.tbhits, 1)
+
+	move := p.solve(3)
+	expect.Eq(t, move.piece(), Piece(Queen))
+	expect.True(t, game.tbhits > 1)
+}
+
+// Compressed table: the position and its mirrored, flipped, and color
+// reversed copies share the same index.
+func TestTablebase070(t *testing.T) {
+	dir := tbCompressed(t, (1 * 63 + 31 - 1) * 62 + 60 - 2) // Kc1,Qh4,Ke8.
+	defer os.RemoveAll(dir)
+	defer NewTablebase(``)
+
+	NewTablebase(dir)
+	for _, position := range [][]string{
+		{ `Kc1,Qh4`, `Ke8` },
+		{ `Kf1,Qa4`, `Kd8` },   // Files mirrored.
+		{ `Kc8,Qh5`, `Ke1` },   // Ranks mirrored.
+		{ `Ka3,Qd8`, `Kh5` },   // Flipped along A1-H8 diagonal.
+		{ `Ke1`, `M,Kc8,Qh5` }, // Colors reversed.
+	} {
+		wdl, ok := NewGame(position[0], position[1]).start().probeWDL()
+		expect.True(t, ok)
+		expect.Eq(t, wdl, tbDraw)
+	}
+
+	// Neighbouring indices.
+	for _, position := range [][]string{
+		{ `Kc1,Qh3`, `Ke8` },
+		{ `Kc1,Qh4`, `Kd8` },
+		{ `Kc1,Qh4`, `Kf8` },
+		{ `Kd1,Qh4`, `Ke8` },
+	} {
+		wdl, ok := NewGame(position[0], position[1]).start().probeWDL()
+		expect.True(t, ok)
+		expect.Eq(t, wdl, tbWin)
+	}
+
+	wdl, ok := NewGame(`Kc1,Qh4`, `M,Ke8`).start().probeWDL()
+	expect.True(t, ok)
+	expect.Eq(t, wdl, tbLoss)
+}
+
+// Losing moves are ranked by the distance to zero unless the fifty moves
+// rule saves the game.
+func TestTablebase080(t *testing.T) {
+	dir := tbSingleValued(t)
+	defer os.RemoveAll(dir)
+	defer NewTablebase(``)
+
+	data := []byte{
+		0xD7, 0x66, 0x0C, 0xA5, // Magic.
+		0x01,                   // No pawns.
+		0x00,                   // Group order.
+		0x66, 0x55, 0xEE,       // White king, white queen, black king.
+		0x00,                   // Word alignment.
+		0x84, 60,               // White to move: single value, win in 61 plies.
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, `KQvK.rtbz`), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	NewTablebase(dir)
+
+	var ranks [128]int
+	gen := NewGen(NewGame(`4k3/8/8/8/3Q4/8/8/4K3 b - - 0 1`).start(), MaxPly).generateAllMoves().validOnly()
+	expect.True(t, gen.rankByDistance(ranks[:]))
+	for i := 0; i < gen.tail; i++ {
+		expect.Eq(t, ranks[i], -1000 + 62)
+	}
+
+	gen = NewGen(NewGame(`4k3/8/8/8/3Q4/8/8/4K3 b - - 40 1`).start(), MaxPly).generateAllMoves().validOnly()
+	expect.True(t, gen.rankByDistance(ranks[:]))
+	for i := 0; i < gen.tail; i++ {
+		expect.Eq(t, ranks[i], -1) // Blessed loss.
+	}
+}
+
+// Real tables, if any.
+func tbPath(t *testing.T, name string) bool {
+	path := os.Getenv(`DONNA_SYZYGY`)
+	if len(path) == 0 {
+		t.Skip(`DONNA_SYZYGY is not set`)
+		return false
+	}
+	if NewTablebase(path); tablebase.tables[newSyzygy(name, name).key] == nil {
+		t.Skip(name + ` table not found`)
+		return false
+	}
+
+	return true
+}
+
+func TestTablebase100(t *testing.T) {
+	defer NewTablebase(``)
+	if tbPath(t, `KQvK`) {
+		wdl, ok := NewGame(`Ke1,Qh1`, `Ke8`).start().probeWDL()
+		expect.True(t, ok)
+		expect.Eq(t, wdl, tbWin)
+
+		wdl, ok = NewGame(`Ke1,Qh1`, `M,Ke8`).start().probeWDL()
+		expect.True(t, ok)
+		expect.Eq(t, wdl, tbLoss)
+
+		dtz, ok := NewGame(`Ke1,Qh1`, `Ke8`).start().probeDTZ()
+		expect.True(t, ok)
+		expect.True(t, dtz > 0 && dtz < 25)
+	}
+}
+
+func TestTablebase110(t *testing.T) {
+	defer NewTablebase(``)
+	if tbPath(t, `KPvK`) {
+		wdl, ok := NewGame(`Ka1,a2`, `Ka8`).start().probeWDL()
+		expect.True(t, ok)
+		expect.Eq(t, wdl, tbDraw)
+
+		wdl, ok = NewGame(`Kd6,e6`, `M,Ke8`).start().probeWDL()
+		expect.True(t, ok)
+		expect.Eq(t, wdl, tbDraw) // 1... Kd8 2. e7+ Ke8 3. Ke6 stalemate.
+	}
+}
+
+func TestTablebase120(t *testing.T) {
+	defer NewTablebase(``)
+	if tbPath(t, `KRvK`) {
+		p := NewGame(`Kc6,Rh1`, `Kc8`).start()
+		game.getReady()
+		gen := NewRootGen(p, 1).generateRootMoves()
+		expect.Eq(t, gen.size(), 1)
+		expect.Eq(t, gen.NextMove(), `Rh1-h8`)
+		expect.True(t, game.tbhits > 0)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`bufio`
+	`fmt`
+	`math`
+	`os`
+	`regexp`
+	`runtime`
+	`strings`
+	`sync`
+)
+
+// Labeled position used for tuning evaluation parameters.
+type TunerPosition struct {
+	position Position 	// Quiescent position the evaluation gets applied to.
+	result   float64 	// Game result from White's point of view: 1.0, 0.5, or 0.0.
+}
+
+// Texel tuner: finds evaluation parameters that minimize the difference between
+// game results and sigmoid-scaled evaluation of the positions from these games.
+type Tuner struct {
+	positions []TunerPosition
+	params    []Parameter
+	k         float64 	// Sigmoid scaling constant.
+	threads   int 		// Number of concurrent evaluation workers.
+	caches    []*PawnCache 	// Pawn cache for each worker.
+}
+
+// Game result formats: c9 "1-0"; c9 "1/2-1/2"; [1.0] [0.5] [0] etc.
+var reTunerResult = regexp.MustCompile(`(1-0|0-1|1/2-1/2|\[[01](\.\d+)?\])`)
+
+// Loads labeled positions from EPD file. Each line contains FEN followed by
+// game result, and the positions get resolved with quiescence search so that
+// evaluation is applied to quiet positions only.
+func NewTuner(fileName string) (*Tuner, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	tuner := &Tuner{ params: evaluationParameters(), k: 1.0, threads: runtime.NumCPU() }
+	for worker := 0; worker < tuner.threads; worker++ {
+		tuner.caches = append(tuner.caches, NewPawnCache(engine.pawnSize))
+	}
+	NewGame()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		result := reTunerResult.FindString(line)
+		fields := strings.Fields(line)
+		if len(result) == 0 || len(fields) < 4 {
+			return nil, fmt.Errorf("invalid position '%s'", line)
+		}
+
+		if position := tuner.resolve(strings.Join(fields[0:4], ` `) + ` 0 1`); position != nil {
+			tuner.positions = append(tuner.positions, TunerPosition{ *position, tunerResult(result) })
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return tuner, nil
+}
+
+// Returns number of positions loaded.
+func (t *Tuner) size() int {
+	return len(t.positions)
+}
+
+// Runs quiescence search and plays out its principal variation to get the
+// quiet position. Positions in check and decided positions get skipped.
+func (t *Tuner) resolve(fen string) *Position {
+	game.initial = fen
+	p := game.start()
+	if p == nil || p.isInCheck(p.color) {
+		return nil
+	}
+
+	game.getReady()
+	if score := p.searchQuiescence(-Checkmate, Checkmate, 0, false); isMate(score) {
+		return nil
+	}
+	for i := 0; i < game.pv[0].size; i++ {
+		p = p.makeMove(game.pv[0].moves[i])
+	}
+
+	return p
+}
+
+// Finds sigmoid scaling constant that minimizes evaluation error for the
+// current parameters.
+func (t *Tuner) optimizeK() float64 {
+	best := t.meanError(t.k)
+	for step := 0.1; step > 0.0001; step /= 10 {
+		for _, delta := range []float64{ step, -step } {
+			for t.k + delta > 0 {
+				loss := t.meanError(t.k + delta)
+				if loss >= best {
+					break
+				}
+				best, t.k = loss, t.k + delta
+			}
+		}
+	}
+
+	return t.k
+}
+
+// Local search: nudges every parameter value by one in both directions and
+// keeps the change if it reduces the evaluation error. The parameters get
+// saved to the given file after each pass until no improvement is found.
+func (t *Tuner) Tune(fileName string) (best float64, err error) {
+	fmt.Printf("Positions: %d\n", t.size())
+	fmt.Printf("        K: %.4f\n", t.optimizeK())
+
+	best = t.meanError(t.k)
+	fmt.Printf("    Error: %.6f\n", best)
+
+	for pass, improved := 1, true; improved; pass++ {
+		improved = false
+		for _, param := range t.params {
+			changed := false
+			for _, value := range param.values {
+				if value == &valuePawn.midgame {
+					continue // Pawn value is what everything else is measured in.
+				}
+				for _, delta := range []int{ 1, -1 } {
+					*value += delta
+					if loss := t.meanError(t.k); loss < best {
+						best, changed = loss, true
+						break
+					}
+					*value -= delta
+				}
+			}
+			if changed {
+				improved = true
+				fmt.Printf("%9s: %s\n", ``, param.name)
+			}
+		}
+
+		fmt.Printf("%9d: %.6f\n", pass, best)
+		if err = saveParameters(fileName, t.params); err != nil {
+			break
+		}
+	}
+	rebuildParameters()
+
+	return
+}
+
+// Returns mean squared error between game results and sigmoid-scaled static
+// evaluation. The positions are evaluated concurrently, each worker using its
+// own pawn cache that gets cleared since parameter changes invalidate cached
+// pawn scores.
+func (t *Tuner) meanError(k float64) float64 {
+	var wait sync.WaitGroup
+
+	rebuildParameters()
+	losses := make([]float64, t.threads)
+	chunk := (len(t.positions) + t.threads - 1) / t.threads
+	for worker := 0; worker < t.threads; worker++ {
+		wait.Add(1)
+		go func(worker int) {
+			defer wait.Done()
+
+			var e Evaluation
+			cache := t.caches[worker]
+			cache.clear()
+			for i := worker * chunk; i < min(len(t.positions), (worker + 1) * chunk); i++ {
+				position := t.positions[i].position
+				position.tally = position.valuation()
+
+				e.init(&position).pawnCache = cache
+				score := e.run()
+				if position.color == Black {
+					score = -score
+				}
+
+				diff := t.positions[i].result - sigmoid(score, k)
+				losses[worker] += diff * diff
+			}
+		}(worker)
+	}
+	wait.Wait()
+
+	sum := 0.0
+	for _, loss := range losses {
+		sum += loss
+	}
+
+	return sum / float64(max(1, len(t.positions)))
+}
+
+// Maps the score to expected game result in 0.0..1.0 range.
+func sigmoid(score int, k float64) float64 {
+	return 1.0 / (1.0 + math.Pow(10.0, -k * float64(score) / 400.0))
+}
+
+// Converts game result string to the number.
+func tunerResult(result string) float64 {
+	switch result {
+	case `1-0`, `[1]`, `[1.0]`:
+		return 1.0
+	case `0-1`, `[0]`, `[0.0]`:
+		return 0.0
+	case `1/2-1/2`:
+		return 0.5
+	}
+
+	var value float64
+	fmt.Sscanf(result, "[%g]", &value)
+	return value
+}
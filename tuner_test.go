@@ -0,0 +1,108 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `bytes`; `io/ioutil`; `os`; `path/filepath`; `strings`; `testing`)
+
+// Writes labeled positions to temporary file.
+func tunerPositions(t *testing.T, dir string) string {
+	fileName := filepath.Join(dir, `positions.epd`)
+	content := "# Labeled positions.\n" +
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 c9 \"1/2-1/2\";\n" +
+		"8/5k2/8/8/3Q4/8/5K2/8 w - - 0 1 [1.0]\n" +
+		"4k3/8/8/8/8/8/4q3/4K3 w - - 0 1 c9 \"0-1\";\n" + // In check, gets skipped.
+		"3k4/8/8/3q4/8/8/3R4/3K4 w - - 0 1 [1.0]\n"       // Rd2xd5 is resolved.
+	if err := ioutil.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return fileName
+}
+
+func TestTuner000(t *testing.T) {
+	expect.Eq(t, tunerResult(`1-0`), 1.0)
+	expect.Eq(t, tunerResult(`0-1`), 0.0)
+	expect.Eq(t, tunerResult(`1/2-1/2`), 0.5)
+	expect.Eq(t, tunerResult(`[0.5]`), 0.5)
+	expect.Eq(t, tunerResult(`[1]`), 1.0)
+}
+
+func TestTuner010(t *testing.T) {
+	expect.Eq(t, sigmoid(0, 1.0), 0.5)
+	expect.True(t, sigmoid(onePawn, 1.0) > 0.5)
+	expect.True(t, sigmoid(-onePawn, 1.0) < 0.5)
+	expect.True(t, sigmoid(onePawn, 1.2) > sigmoid(onePawn, 1.0))
+}
+
+// Parameters get written as name = [ values ].
+func TestTuner020(t *testing.T) {
+	params := evaluationParameters()
+	expect.Eq(t, params[0].name, `valuePawn`)
+	expect.Eq(t, len(params[0].values), 2)
+
+	buffer := new(bytes.Buffer)
+	expect.True(t, writeParameters(buffer, params) == nil)
+	expect.Contain(t, buffer.String(), "\nrookOnOpen = [ 22, 10 ]\n")
+	expect.Contain(t, buffer.String(), "\nbonusPassedPawn = [ 0, 0, 0, 3, 0, 7, 17, 17,")
+}
+
+// Loading and resolving labeled positions.
+func TestTuner030(t *testing.T) {
+	dir, _ := ioutil.TempDir(``, `tuner`)
+	defer os.RemoveAll(dir)
+
+	tuner, err := NewTuner(tunerPositions(t, dir))
+	expect.True(t, err == nil)
+	expect.Eq(t, tuner.size(), 3)
+	expect.Eq(t, tuner.positions[0].result, 0.5)
+	expect.Eq(t, tuner.positions[1].result, 1.0)
+	expect.Eq(t, tuner.positions[2].result, 1.0)
+	expect.Eq(t, tuner.positions[2].position.outposts[BlackQueen], Bitmask(0))
+	expect.True(t, tuner.meanError(1.0) > 0.0)
+
+	_, err = NewTuner(filepath.Join(dir, `missing.epd`))
+	expect.True(t, err != nil)
+}
+
+// Tuning a single parameter.
+func TestTuner040(t *testing.T) {
+	dir, _ := ioutil.TempDir(``, `tuner`)
+	defer os.RemoveAll(dir)
+
+	saved := rightToMove
+	defer func() {
+		rightToMove = saved
+		rebuildParameters()
+	}()
+
+	tuner, _ := NewTuner(tunerPositions(t, dir))
+	tuner.params = []Parameter{{ `rightToMove`, paramScore(&rightToMove) }}
+	before := tuner.meanError(tuner.k)
+
+	paramsFile := filepath.Join(dir, `tuned.params`)
+	after, err := tuner.Tune(paramsFile)
+	expect.True(t, err == nil)
+	expect.True(t, after < before)
+	expect.True(t, rightToMove.midgame < saved.midgame) // Initial position is a draw.
+
+	content, _ := ioutil.ReadFile(paramsFile)
+	expect.True(t, strings.HasPrefix(string(content), `# Donna`))
+	expect.Contain(t, string(content), "\nrightToMove = [ ")
+}
+
+// Worker pawn caches are reused and get cleared before evaluating positions.
+func TestTuner050(t *testing.T) {
+	dir, _ := ioutil.TempDir(``, `tuner`)
+	defer os.RemoveAll(dir)
+
+	tuner, _ := NewTuner(tunerPositions(t, dir))
+	expect.Eq(t, len(tuner.caches), tuner.threads)
+	before, cache := tuner.meanError(1.0), tuner.caches[0]
+
+	id := tuner.positions[0].position.pawnId
+	cache.entries[id & uint64(len(cache.entries) - 1)] = PawnEntry{ id: id, valid: true, score: Score{ 1000, 1000 } }
+	expect.Eq(t, tuner.meanError(1.0), before)
+	expect.True(t, tuner.caches[0] == cache)
+}
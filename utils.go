@@ -67,6 +67,19 @@ func ply() int {
 	return node - rootNode
 }
 
+// Returns draw score from the point of view of the side to move. With positive
+// contempt the side to move at the root avoids draws, and the opponent is
+// assumed to welcome them. Analysis mode keeps the draw score neutral.
+func drawScore(color uint8) int {
+	if engine.analysis || engine.contempt == 0 {
+		return DrawScore
+	}
+	if color == tree[rootNode].color {
+		return DrawScore - engine.contempt * onePawn / 100
+	}
+	return DrawScore + engine.contempt * onePawn / 100
+}
+
 // Returns a score of getting mated in given number of plies.
 func matedIn(ply int) int {
 	return ply - Checkmate
@@ -151,41 +164,34 @@ func C(color uint8) string {
 }
 
 func Summary(metrics map[string]interface{}) {
-	phase := metrics[`Phase`].(int)
-	tally := metrics[`PST`].(Score)
-	material := metrics[`Imbalance`].(Score)
-	final := metrics[`Final`].(Score)
+	phase, _ := metrics[`Phase`].(int)
 	units := float32(onePawn)
 
 	fmt.Println()
 	fmt.Printf("Metric              MidGame        |        EndGame        | Blended\n")
 	fmt.Printf("                W      B     W-B   |    W      B     W-B   |  (%d)  \n", phase)
 	fmt.Printf("-----------------------------------+-----------------------+--------\n")
-	fmt.Printf("%-12s    -      -    %5.2f  |    -      -    %5.2f  >  %5.2f\n", `PST`,
-		float32(tally.midgame)/units, float32(tally.endgame)/units, float32(tally.blended(phase))/units)
-	fmt.Printf("%-12s    -      -    %5.2f  |    -      -    %5.2f  >  %5.2f\n", `Imbalance`,
-		float32(material.midgame)/units, float32(material.endgame)/units, float32(material.blended(phase))/units)
-
-	for _, tag := range([]string{`Tempo`, `Center`, `Threats`, `Pawns`, `Passers`, `Mobility`, `+Pieces`, `-Knights`, `-Bishops`, `-Rooks`, `-Queens`, `+King`, `-Cover`, `-Safety`}) {
-		white := metrics[tag].(Total).white
-		black := metrics[tag].(Total).black
-
-		var score Score
-		score.add(white).sub(black)
-
-		if tag[0:1] == `+` {
-			tag = tag[1:]
-		} else if tag[0:1] == `-` {
-			tag = `  ` + tag[1:]
+
+	for _, term := range traceTerms(metrics) {
+		tag, total := term.Name, term.Total
+		if term.Group != `` {
+			tag = `  ` + tag
+		} else if term.Name == `Final` {
+			tag = `Final Score`
 		}
 
-		fmt.Printf("%-12s  %5.2f  %5.2f  %5.2f  |  %5.2f  %5.2f  %5.2f  >  %5.2f\n", tag,
-			float32(white.midgame)/units, float32(black.midgame)/units, float32(score.midgame)/units,
-			float32(white.endgame)/units, float32(black.endgame)/units, float32(score.endgame)/units,
-			float32(score.blended(phase))/units)
+		if term.White == nil {
+			fmt.Printf("%-12s    -      -    %5.2f  |    -      -    %5.2f  >  %5.2f\n", tag,
+				float32(total.Midgame)/units, float32(total.Endgame)/units, float32(total.Blended)/units)
+		} else {
+			white, black := term.White, term.Black
+			fmt.Printf("%-12s  %5.2f  %5.2f  %5.2f  |  %5.2f  %5.2f  %5.2f  >  %5.2f\n", tag,
+				float32(white.Midgame)/units, float32(black.Midgame)/units, float32(total.Midgame)/units,
+				float32(white.Endgame)/units, float32(black.Endgame)/units, float32(total.Endgame)/units,
+				float32(total.Blended)/units)
+		}
 	}
-	fmt.Printf("%-12s    -      -    %5.2f  |    -      -    %5.2f  >  %5.2f\n\n", `Final Score`,
-		float32(final.midgame)/units, float32(final.endgame)/units, float32(final.blended(phase))/units)
+	fmt.Println()
 }
 
 // Logging wrapper around fmt.Printf() that could be turned on as needed. Typical
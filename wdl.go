@@ -0,0 +1,262 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`bufio`
+	`fmt`
+	`math`
+	`os`
+	`regexp`
+	`strconv`
+	`strings`
+)
+
+// Win/draw/loss model: the chance to win with the score x is 1 / (1 + e^((a - x) / b))
+// where a is the score that gives even chances to win, and b defines how fast
+// the chances grow. Both are cubic polynomials of the game phase scaled to
+// 0..1 range. Use scripts/wdl.sh (or "wdl <file.pgn>" REPL command) to fit
+// the coefficients to self-play games.
+var wdlScore = [4]int{ 166, -108, -11, 121 }  // Polynomial coefficients for a.
+var wdlSpread = [4]int{ 59, 40, 72, 8 }       // Polynomial coefficients for b.
+
+// Score with the game phase and the game result from the point of view of the
+// side the score is for.
+type WdlSample struct {
+	score  int 		// Score in centipawns.
+	phase  int 		// Material phase, see MaterialEntry.
+	result float64 		// Game result: 1.0, 0.5, or 0.0.
+}
+
+// Matches engine score comments like {+0.35/12 1.2s} or {-1.05/20}.
+var reWdlScore = regexp.MustCompile(`^([+-]?\d+\.\d+)/\d+`)
+
+// Returns win, draw, and loss probabilities in permille for the score in
+// centipawns and material phase.
+func wdl(score, phase int) (win, draw, loss int) {
+	if isMate(score) {
+		if score > 0 {
+			return 1000, 0, 0
+		}
+		return 0, 0, 1000
+	}
+
+	win = int(wdlWin(float64(score), phase, wdlScore, wdlSpread) * 1000.0 + 0.5)
+	loss = min(int(wdlWin(float64(-score), phase, wdlScore, wdlSpread) * 1000.0 + 0.5), 1000 - win) // Rounding.
+
+	return win, 1000 - win - loss, loss
+}
+
+// Returns win, draw, and loss probabilities for the current game position.
+func (game *Game) wdl(score int) (win, draw, loss int) {
+	return wdl(score, materialBase[game.position().balance].phase)
+}
+
+// Returns the probability to win with the given score. The score that gives
+// even chances can't be negative, otherwise win and loss probabilities would
+// add up to more than 100%.
+func wdlWin(score float64, phase int, a, b [4]int) float64 {
+	m := float64(min(phase, 256)) / 256.0
+	even := math.Max(((float64(a[3]) * m + float64(a[2])) * m + float64(a[1])) * m + float64(a[0]), 0.0)
+	spread := ((float64(b[3]) * m + float64(b[2])) * m + float64(b[1])) * m + float64(b[0])
+
+	return 1.0 / (1.0 + math.Exp((even - score) / math.Max(spread, 1.0)))
+}
+
+// Fits win/draw/loss model to self-play games and prints the coefficients.
+// The games must have engine scores in move comments, see reWdlScore.
+func FitWdl(fileName string) error {
+	samples, err := readWdlSamples(fileName)
+	if err != nil {
+		return err
+	}
+	if len(samples) == 0 {
+		return fmt.Errorf("no scored moves found in %s", fileName)
+	}
+
+	fmt.Printf("Positions: %d\n", len(samples))
+	a, b, loss := fitWdl(samples)
+	fmt.Printf("     Loss: %.6f\n", loss)
+	fmt.Printf("var wdlScore = [4]int{ %d, %d, %d, %d }\n", a[0], a[1], a[2], a[3])
+	fmt.Printf("var wdlSpread = [4]int{ %d, %d, %d, %d }\n", b[0], b[1], b[2], b[3])
+
+	return nil
+}
+
+// Local search that minimizes negative log-likelihood of the game results,
+// starting with the current model coefficients.
+func fitWdl(samples []WdlSample) (a, b [4]int, best float64) {
+	a, b = wdlScore, wdlSpread
+	best = wdlLoss(samples, a, b)
+
+	for _, step := range []int{ 16, 4, 1 } {
+		for improved := true; improved; {
+			improved = false
+			for _, value := range []*int{ &a[0], &a[1], &a[2], &a[3], &b[0], &b[1], &b[2], &b[3] } {
+				for _, delta := range []int{ step, -step } {
+					*value += delta
+					if loss := wdlLoss(samples, a, b); loss < best {
+						best, improved = loss, true
+						break
+					}
+					*value -= delta
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// Returns average negative log-likelihood of the game results.
+func wdlLoss(samples []WdlSample, a, b [4]int) float64 {
+	sum := 0.0
+	for _, sample := range samples {
+		win := wdlWin(float64(sample.score), sample.phase, a, b)
+		loss := wdlWin(float64(-sample.score), sample.phase, a, b)
+
+		var chance float64
+		switch sample.result {
+		case 1.0:
+			chance = win
+		case 0.0:
+			chance = loss
+		default:
+			chance = 1.0 - win - loss
+		}
+		sum -= math.Log(math.Max(chance, 1e-9))
+	}
+
+	return sum / float64(len(samples))
+}
+
+// Reads PGN file and replays the games collecting scored positions.
+func readWdlSamples(fileName string) (samples []WdlSample, err error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	tags, moves := map[string]string{}, []string{}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64 * 1024), 1024 * 1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, `[`) {
+			// Tag after the moves starts the next game.
+			if len(moves) > 0 {
+				samples = append(samples, wdlGame(tags, moves)...)
+				tags, moves = map[string]string{}, []string{}
+			}
+			if pair := strings.SplitN(strings.Trim(line, `[]`), ` `, 2); len(pair) == 2 {
+				tags[pair[0]] = strings.Trim(pair[1], `"`)
+			}
+		} else if len(line) > 0 {
+			moves = append(moves, line)
+		}
+	}
+	if len(moves) > 0 {
+		samples = append(samples, wdlGame(tags, moves)...)
+	}
+
+	return samples, scanner.Err()
+}
+
+// Replays the game and returns the positions with engine scores. The scores
+// in comments are from the point of view of the side that made the move.
+func wdlGame(tags map[string]string, lines []string) (samples []WdlSample) {
+	var result float64
+
+	switch tags[`Result`] {
+	case `1-0`:
+		result = 1.0
+	case `0-1`:
+		result = 0.0
+	case `1/2-1/2`:
+		result = 0.5
+	default:
+		return nil // Unfinished game.
+	}
+
+	game.initial = `rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1`
+	if fen, ok := tags[`FEN`]; ok {
+		game.initial = fen
+	}
+	p := game.start()
+	if p == nil {
+		return nil
+	}
+
+	text, phase, lastScore := strings.Join(lines, ` `), 0, false
+	for len(text) > 0 {
+		text = strings.TrimSpace(text)
+		if len(text) == 0 {
+			break
+		}
+
+		switch text[0] {
+		case '{':
+			end := strings.IndexByte(text, '}')
+			if end < 0 {
+				return
+			}
+			if match := reWdlScore.FindStringSubmatch(text[1:end]); match != nil && lastScore {
+				if value, err := strconv.ParseFloat(match[1], 64); err == nil {
+					mover := p.color^1 // Comment follows the move.
+					sample := WdlSample{ score: int(value * float64(onePawn)), phase: phase, result: result }
+					if mover == Black {
+						sample.result = 1.0 - result
+					}
+					samples = append(samples, sample)
+				}
+			}
+			text, lastScore = text[end + 1:], false
+		case '(':
+			// Skip variations, including the nested ones.
+			depth, i := 0, 0
+			for ; i < len(text); i++ {
+				if text[i] == '(' {
+					depth++
+				} else if text[i] == ')' {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+			}
+			text = text[min(i + 1, len(text)):]
+		default:
+			token := text
+			if end := strings.IndexAny(text, " {("); end > 0 {
+				token = text[:end]
+			}
+			text = text[len(token):]
+
+			if isGameResult(token) || token[0] == '$' {
+				continue
+			}
+			if dot := strings.LastIndex(token, `.`); dot >= 0 {
+				if token = token[dot + 1:]; len(token) == 0 {
+					continue // Move number.
+				}
+			}
+
+			move := NewMoveFromSan(p, token)
+			if move == Move(0) {
+				return // Illegal move, drop the rest of the game.
+			}
+			phase = materialBase[p.balance].phase
+			p, lastScore = game.makeMove(move), true
+		}
+	}
+
+	return samples
+}
+
+// Returns true if the token is game termination marker.
+func isGameResult(token string) bool {
+	return token == `1-0` || token == `0-1` || token == `1/2-1/2` || token == `*`
+}
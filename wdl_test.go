@@ -0,0 +1,109 @@
+// Copyright (c) 2014-2016 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `io/ioutil`; `os`; `path/filepath`; `strings`; `testing`)
+
+// Win/draw/loss probabilities add up and mirror each other.
+func TestWdl000(t *testing.T) {
+	for _, phase := range []int{ 0, 128, 256 } {
+		for _, score := range []int{ -500, -100, 0, 50, 300 } {
+			win, draw, loss := wdl(score, phase)
+			expect.Eq(t, win + draw + loss, 1000)
+			expect.True(t, draw >= 0)
+
+			other, _, _ := wdl(-score, phase)
+			expect.Eq(t, other, loss)
+		}
+	}
+
+	win, _, _ := wdl(100, 256)
+	more, _, _ := wdl(200, 256)
+	expect.True(t, more > win)
+}
+
+// Mate scores and game phase.
+func TestWdl010(t *testing.T) {
+	win, draw, loss := wdl(Checkmate - 5, 128)
+	expect.Eq(t, win, 1000)
+	expect.Eq(t, draw + loss, 0)
+
+	win, draw, loss = wdl(-Checkmate + 5, 128)
+	expect.Eq(t, loss, 1000)
+	expect.Eq(t, win + draw, 0)
+
+	// Winning score is more decisive in the endgame.
+	endgame, _, _ := wdl(300, 0)
+	opening, _, _ := wdl(300, 256)
+	expect.True(t, endgame > opening)
+}
+
+// Draw chances don't go negative with extreme scores or coefficients.
+func TestWdl015(t *testing.T) {
+	defer func(saved [4]int) { wdlScore = saved }(wdlScore)
+
+	for _, coefficients := range [][4]int{ wdlScore, { -200, 50, -100, 0 } } {
+		wdlScore = coefficients
+		for _, phase := range []int{ 0, 64, 128, 192, 256 } {
+			for _, score := range []int{ -5000, -1000, -1, 0, 1, 1000, 5000 } {
+				win, draw, loss := wdl(score, phase)
+				expect.True(t, draw >= 0)
+				expect.Eq(t, win + draw + loss, 1000)
+			}
+		}
+	}
+}
+
+// Reading scored positions from PGN.
+func TestWdl020(t *testing.T) {
+	dir, _ := ioutil.TempDir(``, `wdl`)
+	defer os.RemoveAll(dir)
+
+	fileName := filepath.Join(dir, `games.pgn`)
+	ioutil.WriteFile(fileName, []byte(`[Event "Self-play"]
+[Result "1-0"]
+
+1. e4 {+0.35/12 0.5s} e5 {-0.20/11 0.4s} 2. Nf3 (2. Qh5 Nc6) 2... Nc6
+{book} 3. Bb5 $1 {+0.50/13} 1-0
+
+[Event "Self-play"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"]
+[Result "1/2-1/2"]
+
+1. Kd2 {+1.00/20} Kd7 {-0.90/20} 1/2-1/2
+
+[Event "Unfinished"]
+[Result "*"]
+
+1. d4 {+0.10/10} *
+
+[Event "Long game"]
+[Result "1/2-1/2"]
+
+` + strings.Repeat(`Nf3 Nf6 Ng1 Ng8 `, 300) + `e4 {+0.25/10} 1/2-1/2
+`), 0644)
+
+	samples, err := readWdlSamples(fileName)
+	expect.True(t, err == nil)
+	expect.Eq(t, len(samples), 6)
+	expect.Eq(t, samples[0], WdlSample{ 35, 256, 1.0 })
+	expect.Eq(t, samples[1], WdlSample{ -20, 256, 0.0 })
+	expect.Eq(t, samples[2], WdlSample{ 50, 256, 1.0 })
+	expect.Eq(t, samples[3], WdlSample{ 100, 0, 0.5 })
+	expect.Eq(t, samples[4], WdlSample{ -90, 0, 0.5 })
+	expect.Eq(t, samples[5], WdlSample{ 25, 256, 0.5 })
+}
+
+// Fitting the model reduces the loss.
+func TestWdl030(t *testing.T) {
+	samples := []WdlSample{}
+	for i := 0; i < 20; i++ {
+		samples = append(samples, WdlSample{ 400, 64, 1.0 }, WdlSample{ -400, 64, 0.0 }, WdlSample{ 20, 64, 0.5 })
+	}
+
+	a, b, loss := fitWdl(samples)
+	expect.True(t, loss < wdlLoss(samples, wdlScore, wdlSpread))
+	expect.Eq(t, loss, wdlLoss(samples, a, b))
+}